@@ -0,0 +1,41 @@
+package miabase
+
+import (
+	"net/http"
+
+	"github.com/danibix95/miabase/pkg/acl"
+	"github.com/danibix95/miabase/pkg/platform"
+	"github.com/danibix95/miabase/pkg/response"
+)
+
+// RequireACL set the authorization expression that the platform user must satisfy to call
+// any route of the plugin, in addition to the expression of each route. It panics when
+// the expression is not valid. Refer to acl.Expression for the supported syntax
+func (p *Plugin) RequireACL(expression string) {
+	p.acl = acl.MustParse(expression)
+}
+
+// withACL wraps the handler so that requests whose user does not satisfy the plugin
+// and the route expressions are rejected with a 403 - Forbidden response.
+// The plugin expression is read on each request, so that it can be set after its routes
+func (p *Plugin) withACL(handler http.HandlerFunc, routeACL *acl.Expression) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		expr := acl.And(p.acl, routeACL)
+		if expr == nil {
+			handler(rw, r)
+			return
+		}
+
+		user, found := platform.User(r.Context())
+		if !found {
+			user = platform.ParseUser(r.Header, platform.HeaderKeys{}.WithDefaults())
+		}
+
+		if !expr.Evaluate(user) {
+			response.Forbidden(rw, r, "user is not allowed to access the requested resource")
+			return
+		}
+
+		handler(rw, r)
+	}
+}
@@ -0,0 +1,107 @@
+package miabase
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteACL(t *testing.T) {
+	newService := func() *Service {
+		s := NewService(ServiceOpts{LogLevel: logLevel})
+
+		plugin := NewPlugin("/orders")
+		plugin.AddRoute(http.MethodGet, "/", func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusNoContent)
+		})
+		plugin.AddRoute(http.MethodDelete, "/{id}", func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusNoContent)
+		}, RouteOpts{ACL: "groups.admin || groups.support && isBackoffice"})
+		plugin.RequireACL("userId")
+		s.Register(plugin)
+
+		return s
+	}
+
+	testCases := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		status  int
+	}{
+		{
+			name:    "allow users satisfying the plugin expression",
+			method:  http.MethodGet,
+			path:    "/orders/",
+			headers: map[string]string{"miauserid": "user-1"},
+			status:  http.StatusNoContent,
+		},
+		{
+			name:   "reject users not satisfying the plugin expression",
+			method: http.MethodGet,
+			path:   "/orders/",
+			status: http.StatusForbidden,
+		},
+		{
+			name:    "allow users satisfying both expressions",
+			method:  http.MethodDelete,
+			path:    "/orders/42",
+			headers: map[string]string{"miauserid": "user-1", "miausergroups": "support", "isbackoffice": "true"},
+			status:  http.StatusNoContent,
+		},
+		{
+			name:    "reject users not satisfying the route expression",
+			method:  http.MethodDelete,
+			path:    "/orders/42",
+			headers: map[string]string{"miauserid": "user-1", "miausergroups": "support"},
+			status:  http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequestWithContext(context.Background(), tc.method, tc.path, nil)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			req.Header.Set("x-request-id", "req-1")
+			rr := httptest.NewRecorder()
+			newService().Inject(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "Status codes mismatch")
+			if tc.status == http.StatusForbidden {
+				verifyJSONResponse(t, rr, map[string]interface{}{
+					"type":      "about:blank",
+					"title":     "Forbidden",
+					"status":    float64(http.StatusForbidden),
+					"detail":    "user is not allowed to access the requested resource",
+					"instance":  tc.path,
+					"requestId": "req-1",
+				})
+			}
+		})
+	}
+
+	t.Run("report expressions within documentation", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, DefaultDocumentationPath, nil)
+		rr := httptest.NewRecorder()
+		newService().Inject(rr, req)
+
+		var doc openapi.Document
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+		require.Equal(t, "userId", doc.Paths["/orders/"]["get"].ACL)
+		require.Equal(t, "userId && (groups.admin || groups.support && isBackoffice)", doc.Paths["/orders/{id}"]["delete"].ACL)
+	})
+
+	t.Run("panic on invalid expressions", func(t *testing.T) {
+		require.Panics(t, func() {
+			NewPlugin("/").AddRoute(http.MethodGet, "/", func(rw http.ResponseWriter, r *http.Request) {}, RouteOpts{ACL: "groups.admin &&"})
+		})
+	})
+}
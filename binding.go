@@ -0,0 +1,43 @@
+package miabase
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/danibix95/miabase/pkg/binding"
+	"github.com/danibix95/miabase/pkg/response"
+)
+
+// TypedHandler is an http handler that receives the incoming request already
+// decoded and validated into the input struct
+type TypedHandler[T any] func(rw http.ResponseWriter, r *http.Request, input T)
+
+// AddTypedRoute add a new endpoint to the plugin, whose handler receives the request input as a T struct.
+// Before executing the handler, the request body, path and query params are bound to the struct
+// and validated following its fields tags (see binding.Bind). When the request does not match
+// the struct, a 400 - Bad Request response listing the invalid fields is returned.
+// It panics when T is not a struct that can be bound (see binding.Check)
+func AddTypedRoute[T any](p *Plugin, method, path string, handler TypedHandler[T], opts ...RouteOpts) {
+	var zero T
+	if err := binding.Check(&zero); err != nil {
+		panic(fmt.Sprintf("typed route %s %s can not be registered: %s", method, path, err))
+	}
+
+	p.AddRoute(method, path, func(rw http.ResponseWriter, r *http.Request) {
+		var input T
+
+		if err := binding.Bind(r, &input); err != nil {
+			var bindErr *binding.Error
+			if errors.As(err, &bindErr) {
+				response.BadRequest(rw, r, bindErr.Message, bindErr.Fields...)
+				return
+			}
+
+			response.BadRequest(rw, r, err.Error())
+			return
+		}
+
+		handler(rw, r, input)
+	}, opts...)
+}
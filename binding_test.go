@@ -0,0 +1,75 @@
+package miabase
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/stretchr/testify/require"
+)
+
+type greetInput struct {
+	Who      string `json:"-" path:"who"`
+	Language string `json:"-" query:"lang" validate:"oneof=en it"`
+	Message  string `json:"message" validate:"required,max=20"`
+}
+
+func TestAddTypedRoute(t *testing.T) {
+	plugin := NewPlugin("/")
+	AddTypedRoute(plugin, http.MethodPost, "/greet/{who}", func(rw http.ResponseWriter, r *http.Request, input greetInput) {
+		response.JSON(rw, map[string]string{"message": fmt.Sprintf("%s %s (%s)", input.Message, input.Who, input.Language)})
+	})
+
+	t.Run("handler receives the bound input", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/greet/mario?lang=it", strings.NewReader(`{"message":"ciao"}`))
+		rr := httptest.NewRecorder()
+
+		plugin.Inject(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, "Status codes mismatch")
+		verifyJSONResponse(t, rr, map[string]interface{}{"message": "ciao mario (it)"})
+	})
+
+	t.Run("invalid requests are rejected before executing the handler", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/greet/mario?lang=fr", strings.NewReader(`{}`))
+		rr := httptest.NewRecorder()
+
+		plugin.Inject(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code, "Status codes mismatch")
+		require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+		verifyJSONResponse(t, rr, map[string]interface{}{
+			"type":     "about:blank",
+			"title":    "Bad Request",
+			"status":   float64(http.StatusBadRequest),
+			"detail":   "request validation failed",
+			"instance": "/greet/mario",
+			"errors": []interface{}{
+				map[string]interface{}{"field": "lang", "in": "query", "message": "must be one of [en it]"},
+				map[string]interface{}{"field": "message", "in": "body", "message": "is required"},
+			},
+		})
+	})
+}
+
+func TestAddTypedRouteRegistration(t *testing.T) {
+	t.Run("panic when the input is not a struct", func(t *testing.T) {
+		require.PanicsWithValue(t, "typed route GET /count can not be registered: binding target must be a pointer to a struct, not *int", func() {
+			AddTypedRoute(NewPlugin("/"), http.MethodGet, "/count", func(rw http.ResponseWriter, r *http.Request, input int) {})
+		})
+	})
+
+	t.Run("panic when the input tags are not valid", func(t *testing.T) {
+		type invalidInput struct {
+			Limit int `query:"limit" validate:"max=ten"`
+		}
+
+		require.Panics(t, func() {
+			AddTypedRoute(NewPlugin("/"), http.MethodGet, "/items", func(rw http.ResponseWriter, r *http.Request, input invalidInput) {})
+		})
+	})
+}
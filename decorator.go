@@ -0,0 +1,21 @@
+package miabase
+
+import (
+	"net/http"
+
+	"github.com/danibix95/miabase/pkg/decorator"
+)
+
+// AddPreDecorator add a POST route that the gateway calls as PRE decorator before forwarding
+// the original request, whose envelope is decoded and passed to the handler.
+// The handler reply, such as decorator.ChangeOriginalRequest, is sent in the gateway format
+func (p *Plugin) AddPreDecorator(path string, handler decorator.PreHandler, opts ...RouteOpts) {
+	p.AddRoute(http.MethodPost, path, decorator.PreHandlerFunc(handler), opts...)
+}
+
+// AddPostDecorator add a POST route that the gateway calls as POST decorator before returning
+// the original response, whose envelope is decoded together with the request and passed to the handler.
+// The handler reply, such as decorator.ChangeOriginalResponse, is sent in the gateway format
+func (p *Plugin) AddPostDecorator(path string, handler decorator.PostHandler, opts ...RouteOpts) {
+	p.AddRoute(http.MethodPost, path, decorator.PostHandlerFunc(handler), opts...)
+}
@@ -0,0 +1,76 @@
+package miabase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/decorator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecorators(t *testing.T) {
+	newService := func() *Service {
+		s := NewService(ServiceOpts{LogLevel: logLevel})
+
+		plugin := NewPlugin("/decorators")
+		plugin.AddPreDecorator("/check-user", func(r *http.Request, pre decorator.Request) decorator.Reply {
+			if pre.Headers.Get("miauserid") == "" {
+				return decorator.AbortChain(http.StatusUnauthorized, map[string]string{"message": "missing user"}, nil)
+			}
+			return decorator.LeaveOriginalUnchanged()
+		})
+		plugin.AddPostDecorator("/add-header", func(r *http.Request, post decorator.PostRequest) decorator.Reply {
+			return decorator.ChangeOriginalResponse(decorator.ResponseChanges{
+				Headers: map[string]string{"x-decorated": "true"},
+			})
+		})
+		s.Register(plugin)
+
+		return s
+	}
+
+	testCases := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		reply  string
+	}{
+		{
+			name:   "pre decorator leaves request unchanged",
+			path:   "/decorators/check-user",
+			body:   `{"method":"GET","path":"/","headers":{"miauserid":"user-1"},"query":{}}`,
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "pre decorator aborts the chain",
+			path:   "/decorators/check-user",
+			body:   `{"method":"GET","path":"/","headers":{},"query":{}}`,
+			status: http.StatusTeapot,
+			reply:  `{"statusCode":401,"headers":{},"body":{"message":"missing user"}}`,
+		},
+		{
+			name:   "post decorator changes the response",
+			path:   "/decorators/add-header",
+			body:   `{"request":{"method":"GET","path":"/","headers":{},"query":{}},"response":{"statusCode":200,"headers":{}}}`,
+			status: http.StatusOK,
+			reply:  `{"headers":{"x-decorated":"true"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, tc.path, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			newService().Inject(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "Status codes mismatch")
+			if tc.reply != "" {
+				require.JSONEq(t, tc.reply, rr.Body.String())
+			}
+		})
+	}
+}
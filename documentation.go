@@ -0,0 +1,113 @@
+package miabase
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/danibix95/miabase/pkg/acl"
+	"github.com/danibix95/miabase/pkg/openapi"
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/danibix95/miabase/pkg/validation"
+)
+
+// DefaultDocumentationPath is the route serving the service OpenAPI document
+const DefaultDocumentationPath = "/documentation/json"
+
+// RouteOpts defines the optional details that describe a plugin route
+// within the OpenAPI document exposed by the service and the schemas its requests must match
+type RouteOpts struct {
+	// Summary is a short description of the route
+	Summary string
+	// Description is a verbose explanation of the route behavior
+	Description string
+	// Tags groups routes within the documentation
+	Tags []string
+	// RequestBody is a value whose Go type describes the JSON body accepted by the route
+	RequestBody interface{}
+	// Responses associates each status code with a value whose Go type describes the JSON response body.
+	// A nil value describes a response without body
+	Responses map[int]interface{}
+	// PathParams associates the route path parameters with their description
+	PathParams map[string]string
+	// QueryParams describes the querystring parameters accepted by the route
+	QueryParams []openapi.Parameter
+	// Schemas are the JSON Schemas that requests and responses of the route are validated against.
+	// Requests not matching them are rejected with a 400 - Bad Request response
+	Schemas validation.Schemas
+	// ACL is the authorization expression that the platform user must satisfy to call the route,
+	// such as `groups.admin || isBackoffice`. Requests not satisfying it are rejected
+	// with a 403 - Forbidden response. Refer to acl.Expression for the supported syntax
+	ACL string
+}
+
+type route struct {
+	method string
+	path   string
+	opts   RouteOpts
+	acl    *acl.Expression
+}
+
+// operation converts the route details into an OpenAPI operation
+func (opts RouteOpts) operation() *openapi.Operation {
+	op := &openapi.Operation{
+		Summary:     opts.Summary,
+		Description: opts.Description,
+		Tags:        opts.Tags,
+	}
+
+	// path params are sorted by name, so that the generated document does not change between runs
+	names := make([]string, 0, len(opts.PathParams))
+	for name := range opts.PathParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op.Parameters = append(op.Parameters, openapi.Parameter{Name: name, In: openapi.InPath, Description: opts.PathParams[name]})
+	}
+	for _, param := range opts.QueryParams {
+		param.In = openapi.InQuery
+		op.Parameters = append(op.Parameters, param)
+	}
+
+	if opts.RequestBody != nil {
+		op.RequestBody = openapi.JSONRequestBody(opts.RequestBody)
+	}
+	if len(opts.Responses) > 0 {
+		op.Responses = openapi.JSONResponses(opts.Responses)
+	}
+
+	return op
+}
+
+// documentation generates the OpenAPI document describing the routes of the registered plugins
+func (s *Service) documentation() *openapi.Document {
+	doc := openapi.NewDocument(s.name, s.version)
+
+	for _, plugin := range s.plugins {
+		for _, r := range plugin.routes {
+			op := r.opts.operation()
+			if expr := acl.And(plugin.acl, r.acl); expr != nil {
+				op.ACL = expr.String()
+			}
+			doc.AddOperation(r.method, joinPaths(plugin.Path, r.path), op)
+		}
+	}
+
+	return doc
+}
+
+// documentationHandler returns the handler serving the OpenAPI document,
+// which is generated once when the handler is created
+func (s *Service) documentationHandler() http.HandlerFunc {
+	doc := s.documentation()
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		response.JSON(rw, doc)
+	}
+}
+
+// joinPaths concatenates the plugin path with the path of one of its routes
+func joinPaths(pluginPath, routePath string) string {
+	return strings.TrimSuffix(pluginPath, "/") + "/" + strings.TrimPrefix(routePath, "/")
+}
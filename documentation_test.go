@@ -0,0 +1,87 @@
+package miabase
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentation(t *testing.T) {
+	type order struct {
+		ID    string  `json:"id"`
+		Total float64 `json:"total"`
+	}
+
+	s := NewService(ServiceOpts{Name: "orders", Version: "v1.0.0", LogLevel: logLevel})
+
+	plugin := NewPlugin("/orders")
+	plugin.AddRoute(http.MethodGet, "/{id}", func(rw http.ResponseWriter, r *http.Request) {}, RouteOpts{
+		Summary:     "get an order",
+		Tags:        []string{"orders"},
+		Responses:   map[int]interface{}{http.StatusOK: order{}},
+		PathParams:  map[string]string{"id": "order identifier"},
+		QueryParams: []openapi.Parameter{{Name: "fields", Description: "projection"}},
+	})
+	plugin.AddRoute(http.MethodPost, "/", func(rw http.ResponseWriter, r *http.Request) {}, RouteOpts{
+		RequestBody: order{},
+	})
+	plugin.AddRoute(http.MethodDelete, "/{id}", func(rw http.ResponseWriter, r *http.Request) {})
+	s.Register(plugin)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, DefaultDocumentationPath, nil)
+	response := httptest.NewRecorder()
+	s.Inject(response, req)
+
+	require.Equal(t, http.StatusOK, response.Code, "Status codes mismatch")
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &doc))
+
+	require.Equal(t, openapi.Info{Title: "orders", Version: "v1.0.0"}, doc.Info)
+	require.Len(t, doc.Paths, 2)
+
+	getOrder := doc.Paths["/orders/{id}"]["get"]
+	require.Equal(t, "get an order", getOrder.Summary)
+	require.Equal(t, []string{"orders"}, getOrder.Tags)
+	require.Equal(t, []openapi.Parameter{
+		{Name: "id", In: openapi.InPath, Description: "order identifier", Required: true, Schema: &openapi.Schema{Type: openapi.TypeString}},
+		{Name: "fields", In: openapi.InQuery, Description: "projection", Schema: &openapi.Schema{Type: openapi.TypeString}},
+	}, getOrder.Parameters)
+	require.Equal(t, []string{"id", "total"}, getOrder.Responses["200"].Content["application/json"].Schema.Required)
+
+	require.NotNil(t, doc.Paths["/orders/"]["post"].RequestBody)
+	require.Contains(t, doc.Paths["/orders/{id}"], "delete", "routes without details are documented as well")
+}
+
+func TestDocumentationPathParamsOrder(t *testing.T) {
+	opts := RouteOpts{
+		PathParams: map[string]string{
+			"shop":    "shop identifier",
+			"order":   "order identifier",
+			"item":    "item identifier",
+			"variant": "variant identifier",
+			"batch":   "batch identifier",
+		},
+		QueryParams: []openapi.Parameter{{Name: "fields"}},
+	}
+
+	// map iteration order is random, so the operation is generated several times
+	for i := 0; i < 20; i++ {
+		names := make([]string, 0, len(opts.PathParams)+1)
+		for _, param := range opts.operation().Parameters {
+			names = append(names, param.Name)
+		}
+		require.Equal(t, []string{"batch", "item", "order", "shop", "variant", "fields"}, names)
+	}
+}
+
+func TestJoinPaths(t *testing.T) {
+	require.Equal(t, "/greet", joinPaths("/", "/greet"))
+	require.Equal(t, "/orders/{id}", joinPaths("/orders", "/{id}"))
+	require.Equal(t, "/orders/", joinPaths("/orders/", "/"))
+}
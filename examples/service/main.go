@@ -71,6 +71,10 @@ func main() {
 		who := chi.URLParam(r, "who")
 
 		response.JSON(rw, map[string]string{"message": fmt.Sprintf("ciaone %s", who)})
+	}, miabase.RouteOpts{
+		Summary:    "greet someone in a friendly way",
+		Responses:  map[int]interface{}{http.StatusOK: map[string]string{}},
+		PathParams: map[string]string{"who": "the person to greet"},
 	})
 
 	service.Register(plugin)
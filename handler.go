@@ -0,0 +1,71 @@
+package miabase
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/danibix95/miabase/pkg/response"
+	zpstd "github.com/danibix95/zeropino/middlewares/std"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrorHandler is an http handler that returns the error preventing it from serving the request.
+// When an error is returned, the handler must not have written the response
+type ErrorHandler func(rw http.ResponseWriter, r *http.Request) error
+
+// AddErrorRoute add a new endpoint to the plugin, whose handler returns the errors it encounters.
+// Returned errors are converted into error responses by the error mapper of the service
+// (see ServiceOpts.ErrorMapper) and logged, with their stack, through the request logger.
+// A meaningful stack is logged only for errors created through github.com/pkg/errors
+// (New, Errorf, Wrap or WithStack), even when wrapped again, while other errors
+// report the stack of the logging site
+func (p *Plugin) AddErrorRoute(method, path string, handler ErrorHandler, opts ...RouteOpts) {
+	p.AddRoute(method, path, func(rw http.ResponseWriter, r *http.Request) {
+		err := handler(rw, r)
+		if err == nil {
+			return
+		}
+
+		problem := p.errorMapper.Problem(err)
+		logHandlerError(r, err, problem.Status)
+		response.WriteProblem(rw, r, problem)
+	}, opts...)
+}
+
+// stackTracer is implemented by the errors created through github.com/pkg/errors
+type stackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+// tracedError exposes the stack of an error wrapped in its chain, since only the stack
+// of the logged error itself is marshaled
+type tracedError struct {
+	error
+	tracer stackTracer
+}
+
+func (e tracedError) StackTrace() pkgerrors.StackTrace {
+	return e.tracer.StackTrace()
+}
+
+func (e tracedError) Unwrap() error {
+	return e.error
+}
+
+// logHandlerError logs the error returned by a handler with the stack recorded where it has been created.
+// Errors not carrying a stack in their chain are logged with the stack of the logging site
+func logHandlerError(r *http.Request, err error, status int) {
+	var tracer stackTracer
+	if !errors.As(err, &tracer) {
+		err = pkgerrors.WithStack(err)
+	} else if _, ok := err.(stackTracer); !ok {
+		err = tracedError{error: err, tracer: tracer}
+	}
+
+	logger := zpstd.Get(r.Context())
+	event := logger.Error()
+	if status < http.StatusInternalServerError {
+		event = logger.Warn()
+	}
+	event.Stack().Err(err).Int("statusCode", status).Msg("request handler returned an error")
+}
@@ -0,0 +1,98 @@
+package miabase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/response"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+var errOrderLocked = errors.New("order locked")
+
+func TestAddErrorRoute(t *testing.T) {
+	newService := func(logs *bytes.Buffer) *Service {
+		s := NewService(ServiceOpts{
+			LogLevel:    logLevel,
+			ErrorMapper: response.NewErrorMapper().Sentinel(errOrderLocked, http.StatusLocked, "order is being processed"),
+		})
+		logger := zerolog.New(logs)
+		s.Logger = &logger
+
+		plugin := NewPlugin("/orders")
+		plugin.AddErrorRoute(http.MethodGet, "/{id}", func(rw http.ResponseWriter, r *http.Request) error {
+			switch r.URL.Query().Get("fail") {
+			case "locked":
+				return fmt.Errorf("loading order: %w", errOrderLocked)
+			case "missing":
+				return &response.NotFoundError{Detail: "order not found"}
+			case "timeout":
+				return fmt.Errorf("loading order: %w", context.DeadlineExceeded)
+			case "unknown":
+				return errors.New("connection refused")
+			case "traced":
+				return fmt.Errorf("loading order: %w", pkgerrors.New("connection reset"))
+			}
+			response.JSON(rw, map[string]string{"id": "42"})
+			return nil
+		})
+		s.Register(plugin)
+
+		return s
+	}
+
+	testCases := []struct {
+		name   string
+		fail   string
+		status int
+		level  string
+		// stackFrom is the function recorded in the first frame of the logged stack
+		stackFrom string
+	}{
+		{name: "serve the request when no error is returned", fail: "", status: http.StatusOK},
+		{name: "map sentinel errors", fail: "locked", status: http.StatusLocked, level: "40"},
+		{name: "map errors providing problem details", fail: "missing", status: http.StatusNotFound, level: "40"},
+		{name: "map context errors", fail: "timeout", status: http.StatusGatewayTimeout, level: "50"},
+		{name: "map unknown errors to internal server error", fail: "unknown", status: http.StatusInternalServerError, level: "50", stackFrom: "logHandlerError"},
+		{name: "log the stack of wrapped traced errors", fail: "traced", status: http.StatusInternalServerError, level: "50", stackFrom: "TestAddErrorRoute"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := new(bytes.Buffer)
+			s := newService(logs)
+
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/orders/42?fail="+tc.fail, nil)
+			rr := httptest.NewRecorder()
+			s.Inject(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "Status codes mismatch")
+
+			failures := 0
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				if strings.Contains(line, "request handler returned an error") {
+					failures++
+					require.Contains(t, line, `"level":"`+tc.level+`"`)
+					require.Contains(t, line, `"stack":[`)
+					if tc.stackFrom != "" {
+						require.Regexp(t, `"stack":\[\{[^}]*"func":"`+tc.stackFrom, line)
+					}
+				}
+			}
+			if tc.status == http.StatusOK {
+				require.Zero(t, failures)
+				return
+			}
+			require.Equal(t, 1, failures, "error is logged once")
+			require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+		})
+	}
+}
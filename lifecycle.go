@@ -0,0 +1,92 @@
+package miabase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+// Hook is a function that the service executes at a specific stage of its lifecycle
+type Hook func(ctx context.Context) error
+
+// lifecycleHooks groups the hooks registered by a plugin for each lifecycle stage
+type lifecycleHooks struct {
+	start    []Hook
+	ready    []Hook
+	shutdown []Hook
+}
+
+// runStartHooks executes the start hooks of each registered plugin, following registration order.
+// It returns the plugins whose start hooks completed successfully, so that only their
+// resources are released, and the error that eventually stopped the startup
+func (s *Service) runStartHooks(ctx context.Context) ([]*Plugin, error) {
+	started := make([]*Plugin, 0, len(s.plugins))
+
+	for _, plugin := range s.plugins {
+		for _, hook := range plugin.hooks.start {
+			if err := hook(ctx); err != nil {
+				return started, fmt.Errorf("plugin %s failed to start: %w", plugin.Path, err)
+			}
+		}
+		started = append(started, plugin)
+	}
+
+	return started, nil
+}
+
+// runReadyHooks executes the ready hooks of provided plugins, following registration order
+func runReadyHooks(ctx context.Context, plugins []*Plugin) error {
+	for _, plugin := range plugins {
+		for _, hook := range plugin.hooks.ready {
+			if err := hook(ctx); err != nil {
+				return fmt.Errorf("plugin %s failed to get ready: %w", plugin.Path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// runShutdownHooks executes the shutdown hooks of provided plugins in reverse registration order.
+// All the hooks are executed even when some of them fail: each error is logged
+// and the first one is returned to the caller
+func runShutdownHooks(ctx context.Context, plugins []*Plugin, log *zerolog.Logger) error {
+	var firstErr error
+
+	for i := len(plugins) - 1; i >= 0; i-- {
+		plugin := plugins[i]
+		for j := len(plugin.hooks.shutdown) - 1; j >= 0; j-- {
+			if err := runBoundedHook(ctx, plugin.hooks.shutdown[j]); err != nil {
+				err = fmt.Errorf("plugin %s failed to shutdown: %w", plugin.Path, err)
+				log.Error().Err(err).Msg("shutdown hook did not work as expected")
+
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// runBoundedHook executes the hook, returning as soon as the context is done
+// even when the hook does not honour the context cancellation
+func runBoundedHook(ctx context.Context, hook Hook) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- hook(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,132 @@
+package miabase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	t.Run("Execute hooks following registration order", func(t *testing.T) {
+		s := NewService(ServiceOpts{HTTPPort: httpPort, LogLevel: logLevel})
+		calls := make([]string, 0)
+		record := func(call string) Hook {
+			return func(ctx context.Context) error {
+				calls = append(calls, call)
+				return nil
+			}
+		}
+
+		first := NewPlugin("/first")
+		first.OnStart(record("first-start"))
+		first.OnReady(record("first-ready"))
+		first.OnShutdown(record("first-shutdown-1"))
+		first.OnShutdown(record("first-shutdown-2"))
+
+		second := NewPlugin("/second")
+		second.OnStart(record("second-start"))
+		second.OnReady(record("second-ready"))
+		second.OnShutdown(record("second-shutdown"))
+
+		s.Register(first)
+		s.Register(second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			cancel()
+		}()
+
+		require.NoError(t, s.Run(ctx))
+		require.Equal(t, []string{
+			"first-start", "second-start",
+			"first-ready", "second-ready",
+			"second-shutdown", "first-shutdown-2", "first-shutdown-1",
+		}, calls)
+	})
+
+	t.Run("Abort startup when a start hook fails", func(t *testing.T) {
+		s := NewService(ServiceOpts{HTTPPort: httpPort, LogLevel: logLevel})
+		startErr := errors.New("connection refused")
+		calls := make([]string, 0)
+
+		first := NewPlugin("/first")
+		first.OnShutdown(func(ctx context.Context) error {
+			calls = append(calls, "first-shutdown")
+			return nil
+		})
+
+		second := NewPlugin("/second")
+		second.OnStart(func(ctx context.Context) error {
+			return startErr
+		})
+		second.OnReady(func(ctx context.Context) error {
+			calls = append(calls, "second-ready")
+			return nil
+		})
+		second.OnShutdown(func(ctx context.Context) error {
+			calls = append(calls, "second-shutdown")
+			return nil
+		})
+
+		s.Register(first)
+		s.Register(second)
+
+		err := s.Run(context.Background())
+		require.ErrorIs(t, err, startErr)
+		require.Equal(t, []string{"first-shutdown"}, calls, "only started plugins are torn down")
+	})
+
+	t.Run("Return shutdown hooks errors", func(t *testing.T) {
+		s := NewService(ServiceOpts{HTTPPort: httpPort, LogLevel: logLevel})
+		shutdownErr := errors.New("pool already closed")
+		executed := false
+
+		first := NewPlugin("/first")
+		first.OnShutdown(func(ctx context.Context) error {
+			executed = true
+			return nil
+		})
+
+		second := NewPlugin("/second")
+		second.OnShutdown(func(ctx context.Context) error {
+			return shutdownErr
+		})
+
+		s.Register(first)
+		s.Register(second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			cancel()
+		}()
+
+		require.ErrorIs(t, s.Run(ctx), shutdownErr)
+		require.True(t, executed, "failing hooks do not prevent others from running")
+	})
+
+	t.Run("Bound shutdown hooks by the grace period", func(t *testing.T) {
+		s := NewService(ServiceOpts{HTTPPort: httpPort, LogLevel: logLevel, ShutdownGracePeriod: 200 * time.Millisecond})
+
+		plugin := NewPlugin("/")
+		plugin.OnShutdown(func(ctx context.Context) error {
+			time.Sleep(5 * time.Second)
+			return nil
+		})
+		s.Register(plugin)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		require.ErrorIs(t, s.Run(ctx), context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 2*time.Second)
+	})
+}
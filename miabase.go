@@ -2,16 +2,25 @@ package miabase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/danibix95/miabase/pkg/client"
 	"github.com/danibix95/miabase/pkg/metrics"
+	"github.com/danibix95/miabase/pkg/platform"
+	"github.com/danibix95/miabase/pkg/requestid"
 	"github.com/danibix95/miabase/pkg/response"
 	"github.com/danibix95/miabase/pkg/status"
+	"github.com/danibix95/miabase/pkg/tracing"
+	"github.com/danibix95/miabase/pkg/validation"
 	"github.com/danibix95/zeropino"
 	zpstd "github.com/danibix95/zeropino/middlewares/std"
 	"github.com/go-chi/chi/v5"
@@ -27,12 +36,32 @@ import (
 type Service struct {
 	name            string
 	version         string
+	httpPort        int
+	docsPath        string
+	responseMode    validation.ResponseMode
+	maxBodySize     int64
+	platformHeaders platform.HeaderKeys
+	clientTimeout   time.Duration
+	requestIDHeader string
+	errorFormat     response.ErrorFormat
+	errorMapper     *response.ErrorMapper
+	clientMetrics   *client.Metrics
 	router          *chi.Mux
 	plugins         []*Plugin
 	statusManager   status.Status
+	statusRegistry  *status.Registry
 	signalReceiver  chan os.Signal
 	metricsRegistry *prometheus.Registry
 	metricsFactory  promauto.Factory
+	requestMetrics  *metrics.RequestMetrics
+	tracing         tracing.Opts
+	shutdown        shutdownOpts
+	// setupOnce ensures that routes and plugins are mounted on the router only once
+	setupOnce sync.Once
+	// starting is set to 1 while plugins start hooks are executed, so that the service is not reported as started
+	starting int32
+	// draining is set to 1 once the shutdown starts, so that the service is not reported as ready anymore
+	draining int32
 	// Logger a zerolog instance that can be employed to log service details within plugins
 	Logger *zerolog.Logger
 }
@@ -43,14 +72,78 @@ type ServiceOpts struct {
 	Name string
 	// Version is a semver string that represents the current version of deployed service
 	Version string
+	// HTTPPort is the port on which the service webserver listens when launched with Run
+	HTTPPort int
+	// DocumentationPath is the route serving the OpenAPI document that describes the plugins routes.
+	// Defaults to DefaultDocumentationPath
+	DocumentationPath string
+	// ResponseValidation defines how responses not matching the schemas of their route are handled.
+	// It is meant for development environments, since responses whose status has a schema are buffered
+	// to be validated, so that flushing them has no effect until the handler returns.
+	// Defaults to validation.ResponseValidationOff
+	ResponseValidation validation.ResponseMode
+	// MaxBodySize is the maximum size in bytes of the request bodies validated against the route schemas,
+	// beyond which requests are rejected with 413 Request Entity Too Large.
+	// Defaults to validation.DefaultMaxBodySize
+	MaxBodySize int64
+	// PlatformHeaders defines the names of the headers carrying the Mia-Platform user details,
+	// which are parsed into a platform.PlatformUser available in the requests context.
+	// Missing names fall back to the platform defaults, while they can be loaded
+	// from the environment through LoadEnv and platform.HeaderKeysEnvConfig
+	PlatformHeaders platform.HeaderKeys
+	// ClientTimeout is the maximum duration of the requests performed by the clients
+	// obtained through client.FromContext, which forward platform headers and request ID
+	// to other services. Defaults to client.DefaultTimeout
+	ClientTimeout time.Duration
+	// RequestIDHeader is the header carrying the request id, which is generated when missing,
+	// echoed in the response and reported in error responses. Defaults to requestid.DefaultHeader
+	RequestIDHeader string
+	// ErrorFormat selects the body of error responses. Defaults to RFC 7807 problem details,
+	// while response.LegacyFormat restores the message and code body
+	ErrorFormat response.ErrorFormat
+	// ErrorMapper converts the errors returned by the handlers registered through AddErrorRoute
+	// into error responses. Defaults to the conversions applied by a mapper without rules
+	ErrorMapper *response.ErrorMapper
 	// LogLevel is a string indicating the minimum log level that is shown on the standard out
 	LogLevel string
-	// StatusManager is an interface providing the three status routes handlers
+	// StatusManager is an interface providing the three status routes handlers, which can
+	// optionally implement the status.StartupStatus interface to provide the startup route handler.
+	// When not provided, status routes execute the checks registered by the service plugins
 	StatusManager status.Status
 	// MetricsManager is an interface providing a method to register custom metrics in the service registry
 	MetricsManager metrics.Metrics
+	// RequestMetrics customizes the metrics collected for the requests received by the service,
+	// such as their buckets, names and namespace. Service name and version are added
+	// to their constant labels as service_name and service_version
+	RequestMetrics metrics.RequestMetricsOpts
+	// Tracing enables OpenTelemetry spans for the requests received by the service and
+	// performed by its clients. Spans are flushed during the graceful shutdown
+	Tracing tracing.Opts
+	// ShutdownGracePeriod is the maximum time given to in-flight requests to complete
+	// once the webserver stops accepting new connections. Defaults to 30 seconds
+	ShutdownGracePeriod time.Duration
+	// ShutdownDrainPeriod is the time during which the service keeps serving requests
+	// while its readiness route reports it as not ready, so that probes can remove it from the load balancing
+	ShutdownDrainPeriod time.Duration
+	// ShutdownPreStopDelay is an additional wait between the drain phase and the webserver shutdown,
+	// which lets load balancers and proxies apply the endpoints changes
+	ShutdownPreStopDelay time.Duration
 }
 
+type shutdownOpts struct {
+	gracePeriod  time.Duration
+	drainPeriod  time.Duration
+	preStopDelay time.Duration
+}
+
+const defaultShutdownGracePeriod = 30 * time.Second
+
+// tracingFlushTimeout bounds the export of the last spans, which is performed once the grace period may have elapsed
+const tracingFlushTimeout = 5 * time.Second
+
+// LoadEnv reads the environment variables described by the configuration into the env struct,
+// panicking when they can not be loaded. Platform headers names can be loaded into
+// a platform.HeaderKeys struct by providing platform.HeaderKeysEnvConfig as configuration
 func LoadEnv(c []configlib.EnvConfig, env interface{}) {
 	if err := configlib.GetEnvVariables(c, &env); err != nil {
 		panic(err.Error())
@@ -64,6 +157,21 @@ func NewService(opts ServiceOpts) *Service {
 	s.router = chi.NewRouter()
 	s.name = opts.Name
 	s.version = opts.Version
+	s.httpPort = opts.HTTPPort
+	s.responseMode = opts.ResponseValidation
+	s.maxBodySize = opts.MaxBodySize
+	s.platformHeaders = opts.PlatformHeaders.WithDefaults()
+	s.clientTimeout = opts.ClientTimeout
+	s.errorFormat = opts.ErrorFormat
+	s.errorMapper = opts.ErrorMapper
+	s.requestIDHeader = opts.RequestIDHeader
+	if s.requestIDHeader == "" {
+		s.requestIDHeader = requestid.DefaultHeader
+	}
+	s.docsPath = opts.DocumentationPath
+	if s.docsPath == "" {
+		s.docsPath = DefaultDocumentationPath
+	}
 
 	logger, err := zeropino.Init(zeropino.InitOptions{Level: opts.LogLevel})
 	if err != nil {
@@ -71,35 +179,107 @@ func NewService(opts ServiceOpts) *Service {
 	}
 	s.Logger = logger
 
+	s.statusRegistry = status.NewRegistry()
 	if opts.StatusManager == nil {
-		s.statusManager = status.DefaultStatus{}
+		s.statusManager = s.statusRegistry
 	} else {
 		s.statusManager = opts.StatusManager
 	}
 
+	s.tracing = opts.Tracing.WithDefaults()
+
 	s.metricsRegistry, s.metricsFactory = metrics.InitializeMetrics(true)
+	s.requestMetrics = metrics.NewRequestMetrics(s.metricsFactory, s.requestMetricsOpts(opts.RequestMetrics))
+	s.statusRegistry.RegisterMetrics(s.metricsFactory)
+	s.clientMetrics = client.NewMetrics(s.metricsFactory)
 	if opts.MetricsManager != nil {
 		opts.MetricsManager.Register(s.metricsFactory)
 	}
 
 	s.signalReceiver = make(chan os.Signal, 1)
 
+	s.shutdown = shutdownOpts{
+		gracePeriod:  opts.ShutdownGracePeriod,
+		drainPeriod:  opts.ShutdownDrainPeriod,
+		preStopDelay: opts.ShutdownPreStopDelay,
+	}
+	if s.shutdown.gracePeriod <= 0 {
+		s.shutdown.gracePeriod = defaultShutdownGracePeriod
+	}
+
 	return s
 }
 
+// requestMetricsOpts adds service name and version to the constant labels of request metrics,
+// unless they have been explicitly provided. When tracing is enabled, exemplars carry the request trace id
+func (s *Service) requestMetricsOpts(opts metrics.RequestMetricsOpts) metrics.RequestMetricsOpts {
+	constLabels := make(prometheus.Labels, len(opts.ConstLabels)+2)
+	if s.name != "" {
+		constLabels["service_name"] = s.name
+	}
+	if s.version != "" {
+		constLabels["service_version"] = s.version
+	}
+	for name, value := range opts.ConstLabels {
+		constLabels[name] = value
+	}
+	opts.ConstLabels = constLabels
+
+	if s.tracing.Enabled() && opts.Exemplar == nil {
+		opts.Exemplar = func(r *http.Request) prometheus.Labels {
+			if traceID := tracing.TraceID(r.Context()); traceID != "" {
+				return prometheus.Labels{"trace_id": traceID}
+			}
+			return metrics.RequestExemplar(r)
+		}
+	}
+
+	return opts
+}
+
 // Register include the new plugin into the set of plugins that the service must load.
 func (s *Service) Register(plugin *Plugin) {
+	plugin.responseValidation = s.responseMode
+	plugin.maxBodySize = s.maxBodySize
+	plugin.errorMapper = s.errorMapper
 	s.plugins = append(s.plugins, plugin)
 }
 
-// Start launch the configured service,
-// mounting customized plugin and starting the webserver
+// Start launch the configured service on the given port,
+// mounting customized plugin and starting the webserver.
+// The service is stopped when a SIGINT or SIGTERM signal is received,
+// while any error encountered during its execution terminates the process.
 func (s *Service) Start(httpPort int) {
+	s.httpPort = httpPort
+
+	// Listen for syscall signals for process to interrupt/quit
+	signal.Notify(s.signalReceiver, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(s.signalReceiver)
+
+	if err := s.Run(context.Background()); err != nil {
+		s.Logger.Fatal().Err(err).Msg("service terminated unexpectedly")
+	}
+}
+
+// Run launch the configured service, mounting customized plugin and starting the webserver.
+// It blocks until the provided context is cancelled or Stop is called, then it gracefully
+// shuts the webserver down. Errors raised while listening or shutting down are returned to the caller.
+func (s *Service) Run(ctx context.Context) error {
 	s.setupServicePlugins()
 
-	server := &http.Server{Addr: fmt.Sprintf("0.0.0.0:%d", httpPort), Handler: s.router}
+	// streamed responses are stopped as soon as the shutdown starts, since the server
+	// waits for them to complete before closing their connections
+	shutdown := make(chan struct{})
+	server := &http.Server{
+		Addr:    fmt.Sprintf("0.0.0.0:%d", s.httpPort),
+		Handler: s.router,
+		BaseContext: func(net.Listener) context.Context {
+			return response.WithShutdown(context.Background(), shutdown)
+		},
+	}
+	server.RegisterOnShutdown(func() { close(shutdown) })
 
-	runWithGracefulShutdown(server, s.Logger, s.signalReceiver)
+	return s.runWithGracefulShutdown(ctx, server)
 }
 
 // Stop terminates service webserver execution
@@ -114,13 +294,38 @@ func (s *Service) Inject(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
 
+// setupServicePlugins mounts service routes and registered plugins on the router.
+// Setup is performed only once, so that the service can be run or injected repeatedly
 func (s *Service) setupServicePlugins() {
+	s.setupOnce.Do(s.setupRouter)
+}
+
+func (s *Service) setupRouter() {
+	s.registerPluginsChecks()
+	// request ids are assigned first, so that every response and error body carries them
+	s.router.Use(requestid.Middleware(s.requestIDHeader))
+	s.router.Use(response.ErrorFormatMiddleware(s.errorFormat))
 	s.addErrorsHandlers()
-	s.router.Use(metrics.RequestStatus(s.metricsFactory))
+	// spans are created before collecting metrics, so that exemplars can refer to them
+	s.router.Use(tracing.Middleware(s.tracing))
+	s.router.Use(s.requestMetrics.RequestStatus())
 	s.addStatusRoutes()
+	s.router.Get(s.docsPath, s.documentationHandler())
 
 	s.router.Group(func(r chi.Router) {
+		r.Use(s.pluginsGate)
+		// the request logger writer hides the optional interfaces of the original one, which are restored
+		r.Use(keepResponseWriter)
 		r.Use(zpstd.RequestLogger(s.Logger, []string{"/-/"}))
+		r.Use(restoreResponseWriter)
+		r.Use(tracing.Logger)
+		r.Use(platform.Middleware(s.platformHeaders))
+		r.Use(client.Middleware(client.Config{
+			Headers: append(s.platformHeaders.Names(), s.requestIDHeader),
+			Timeout: s.clientTimeout,
+			Metrics: s.clientMetrics,
+			Tracing: s.tracing,
+		}))
 
 		for _, plugin := range s.plugins {
 			r.Mount(plugin.Path, plugin.router)
@@ -128,6 +333,20 @@ func (s *Service) setupServicePlugins() {
 	})
 }
 
+func (s *Service) registerPluginsChecks() {
+	for _, plugin := range s.plugins {
+		if len(plugin.checks) == 0 {
+			continue
+		}
+		if s.statusManager != s.statusRegistry {
+			s.Logger.Warn().Msg(fmt.Sprintf("checks of plugin %s are ignored since a custom status manager is in use", plugin.Path))
+			continue
+		}
+
+		s.statusRegistry.Register(plugin.checks...)
+	}
+}
+
 func (s *Service) addErrorsHandlers() {
 	s.router.Use(response.PanicManager)
 	s.router.NotFound(response.NotFound)
@@ -139,51 +358,165 @@ func (s *Service) addStatusRoutes() {
 		statusAndMetricsRouter := chi.NewRouter()
 
 		statusAndMetricsRouter.Get("/healthz", s.statusManager.Health(s.name, s.version))
-		statusAndMetricsRouter.Get("/ready", s.statusManager.Ready(s.name, s.version))
+		statusAndMetricsRouter.Get("/ready", s.startupGate(s.readinessGate(s.statusManager.Ready(s.name, s.version))))
 		statusAndMetricsRouter.Get("/check-up", s.statusManager.CheckUp(s.name, s.version))
+		statusAndMetricsRouter.Get("/startup", s.startupGate(s.startupHandler()))
 
-		statusAndMetricsRouter.Handle("/metrics", promhttp.HandlerFor(s.metricsRegistry, promhttp.HandlerOpts{}))
+		statusAndMetricsRouter.Handle("/metrics", promhttp.HandlerFor(s.metricsRegistry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
 
 		r.Mount("/-/", statusAndMetricsRouter)
 	})
 }
 
-func runWithGracefulShutdown(srv *http.Server, log *zerolog.Logger, sig chan os.Signal) {
-	// Server run context
-	serverCtx, serverStopCtx := context.WithCancel(context.Background())
+// startupHandler returns the startup route handler of the status manager, when it provides one.
+// Otherwise the service is considered started as soon as its plugins start hooks are completed
+func (s *Service) startupHandler() http.HandlerFunc {
+	if startupStatus, ok := s.statusManager.(status.StartupStatus); ok {
+		return startupStatus.Startup(s.name, s.version)
+	}
 
-	// Listen for syscall signals for process to interrupt/quit
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	return func(rw http.ResponseWriter, r *http.Request) {
+		status.JSONResponse(rw, status.Response{Name: s.name, Version: s.version, Status: "OK"})
+	}
+}
 
-	go func() {
-		<-sig
+// startupGate reports the service as unavailable while plugins start hooks are executed,
+// otherwise it delegates the status computation to the provided handler
+func (s *Service) startupGate(next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&s.starting) == 1 {
+			status.JSONResponseWithStatus(rw, http.StatusServiceUnavailable, status.Response{Name: s.name, Version: s.version, Status: "KO"})
+			return
+		}
 
-		// Shutdown signal with grace period of 30 seconds
-		shutdownCtx, shutdownStopCtx := context.WithTimeout(serverCtx, 30*time.Second)
+		next(rw, r)
+	}
+}
 
-		go func() {
-			<-shutdownCtx.Done()
-			if shutdownCtx.Err() == context.DeadlineExceeded {
-				log.Error().Msg("graceful shutdown timed out.. forcing exit")
-			}
-		}()
+// pluginsGate rejects the requests to plugin routes while plugins start hooks are executed,
+// since the resources they initialize are not available yet
+func (s *Service) pluginsGate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&s.starting) == 1 {
+			response.WriteProblem(rw, r, response.NewProblem(http.StatusServiceUnavailable, "service is starting"))
+			return
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
 
-		// Trigger graceful shutdown
-		err := srv.Shutdown(shutdownCtx)
-		if err != nil {
-			log.Fatal().Err(err).Msg("server shutdown did not work as expected")
+// readinessGate reports the service as not ready once its shutdown has started,
+// otherwise it delegates the readiness computation to the provided handler
+func (s *Service) readinessGate(next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&s.draining) == 1 {
+			status.JSONResponseWithStatus(rw, http.StatusServiceUnavailable, status.Response{Name: s.name, Version: s.version, Status: "KO"})
+			return
 		}
 
-		serverStopCtx()
-		shutdownStopCtx()
-	}()
+		next(rw, r)
+	}
+}
+
+func (s *Service) runWithGracefulShutdown(ctx context.Context, srv *http.Server) error {
+	log := s.Logger
+
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("server closed unexpectedly: %w", err)
+	}
+
+	serveErr := make(chan error, 1)
+
+	// The service is not reported as started until plugins resources are initialized
+	atomic.StoreInt32(&s.starting, 1)
 
 	// Run the server
-	log.Info().Msg(fmt.Sprintf("server listening at %s", srv.Addr))
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatal().Err(err).Msg("server closed unexpectedly")
+	go func() {
+		log.Info().Msg(fmt.Sprintf("server listening at %s", srv.Addr))
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	started, runErr := s.runStartHooks(ctx)
+	atomic.StoreInt32(&s.starting, 0)
+
+	// Background checks are launched once plugins resources are available
+	checksCtx, stopChecks := context.WithCancel(context.Background())
+	defer stopChecks()
+	if runErr == nil {
+		s.statusRegistry.Start(checksCtx)
+		runErr = runReadyHooks(ctx, started)
+	}
+
+	if runErr == nil {
+		select {
+		case err := <-serveErr:
+			if err != nil {
+				runErr = fmt.Errorf("server closed unexpectedly: %w", err)
+			}
+		case <-ctx.Done():
+			s.drain()
+		case <-s.signalReceiver:
+			s.drain()
+		}
+	}
+	defer atomic.StoreInt32(&s.draining, 0)
+
+	// Shutdown with the configured grace period. The run context is already done,
+	// therefore the shutdown one must not be derived from it
+	log.Info().Msg(fmt.Sprintf("shutting down server with grace period of %s", s.shutdown.gracePeriod))
+	shutdownCtx, shutdownStopCtx := context.WithTimeout(context.Background(), s.shutdown.gracePeriod)
+	defer shutdownStopCtx()
+
+	// Trigger graceful shutdown
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Error().Msg("graceful shutdown timed out.. forcing exit")
+			_ = srv.Close()
+		}
+		if runErr == nil {
+			runErr = fmt.Errorf("server shutdown did not work as expected: %w", err)
+		}
+	}
+
+	// Wait for the listener to be released, so that the service can be launched again
+	<-serveErr
+
+	// Release plugins resources once no request is served anymore
+	stopChecks()
+	if err := runShutdownHooks(shutdownCtx, started, log); err != nil && runErr == nil {
+		runErr = err
+	}
+
+	// Export the spans of the last served requests, even when shutdown and hooks used up the grace period
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	defer cancelFlush()
+	if err := tracing.Flush(flushCtx, s.tracing.TracerProvider); err != nil {
+		log.Error().Err(err).Msg("tracing flush failed")
+	}
+
+	log.Info().Msg("server shutdown completed")
+
+	return runErr
+}
+
+// drain stops reporting the service as ready while it keeps serving incoming requests,
+// then it waits for the configured drain period and pre-stop delay
+func (s *Service) drain() {
+	log := s.Logger
+
+	atomic.StoreInt32(&s.draining, 1)
+	if s.shutdown.drainPeriod > 0 {
+		log.Info().Msg(fmt.Sprintf("shutdown started: draining traffic for %s", s.shutdown.drainPeriod))
+		time.Sleep(s.shutdown.drainPeriod)
 	}
 
-	// Wait for server context to be stopped
-	<-serverCtx.Done()
+	if s.shutdown.preStopDelay > 0 {
+		log.Info().Msg(fmt.Sprintf("waiting pre-stop delay of %s", s.shutdown.preStopDelay))
+		time.Sleep(s.shutdown.preStopDelay)
+	}
 }
@@ -3,15 +3,25 @@ package miabase
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/danibix95/miabase/pkg/client"
+	"github.com/danibix95/miabase/pkg/metrics"
+	"github.com/danibix95/miabase/pkg/platform"
+	"github.com/danibix95/miabase/pkg/requestid"
 	"github.com/danibix95/miabase/pkg/response"
+	"github.com/danibix95/miabase/pkg/status"
+	"github.com/danibix95/miabase/pkg/tracing"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 const (
@@ -75,7 +85,7 @@ func TestPanicHandler(t *testing.T) {
 // TestServiceStart verifies that the bare bone service
 // is able to start and to terminate gracefully
 func TestServiceStart(t *testing.T) {
-	s := NewService(ServiceOpts{"test-service", "v0.0.1", logLevel, nil, nil})
+	s := NewService(ServiceOpts{Name: "test-service", Version: "v0.0.1", LogLevel: logLevel})
 
 	go func() {
 		time.Sleep(300 * time.Millisecond)
@@ -99,6 +109,586 @@ func TestServiceStart(t *testing.T) {
 	}
 }
 
+// TestPluginChecks verifies that checks registered by plugins
+// are executed by the service status routes
+func TestPluginChecks(t *testing.T) {
+	s := NewService(ServiceOpts{Name: "test-service", Version: "v0.0.1", LogLevel: logLevel})
+
+	plugin := NewPlugin("/")
+	plugin.AddCheck(status.Check{
+		Name:     "mongo",
+		Critical: true,
+		Func: func(ctx context.Context) error {
+			return errors.New("connection refused")
+		},
+	})
+	s.Register(plugin)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/ready", nil)
+	response := httptest.NewRecorder()
+	s.Inject(response, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, response.Code, "Status codes mismatch")
+
+	var res status.Response
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &res))
+	require.Equal(t, "KO", res.Status)
+	require.Len(t, res.Checks, 1)
+	require.Equal(t, "mongo", res.Checks[0].Name)
+}
+
+// TestPlatformUser verifies that plugin routes receive
+// the user details forwarded through the platform headers
+func TestPlatformUser(t *testing.T) {
+	s := NewService(ServiceOpts{
+		Name:            "test-service",
+		Version:         "v0.0.1",
+		LogLevel:        logLevel,
+		PlatformHeaders: platform.HeaderKeys{UserID: "x-user-id"},
+	})
+
+	var user platform.PlatformUser
+	plugin := NewPlugin("/")
+	plugin.AddRoute("GET", "/me", func(rw http.ResponseWriter, r *http.Request) {
+		user, _ = platform.User(r.Context())
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	s.Register(plugin)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/me", nil)
+	req.Header.Set("x-user-id", "user-1")
+	req.Header.Set("miausergroups", "admin,support")
+	req.Header.Set("isbackoffice", "true")
+	response := httptest.NewRecorder()
+	s.Inject(response, req)
+
+	require.Equal(t, http.StatusNoContent, response.Code, "Status codes mismatch")
+	require.Equal(t, "user-1", user.ID)
+	require.Equal(t, []string{"admin", "support"}, user.Groups)
+	require.True(t, user.IsBackOffice)
+}
+
+// TestServiceClient verifies that clients obtained while handling
+// plugin routes forward the platform headers to other services
+func TestServiceClient(t *testing.T) {
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	s := NewService(ServiceOpts{LogLevel: logLevel, PlatformHeaders: platform.HeaderKeys{UserID: "x-user-id"}})
+
+	plugin := NewPlugin("/")
+	plugin.AddRoute("GET", "/proxy", func(rw http.ResponseWriter, r *http.Request) {
+		c := client.FromContext(r.Context()).New(upstream.URL)
+		req, err := c.NewRequest(r.Context(), http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		rw.WriteHeader(res.StatusCode)
+	})
+	s.Register(plugin)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/proxy", nil)
+	req.Header.Set("x-user-id", "user-1")
+	req.Header.Set("isbackoffice", "true")
+	req.Header.Set("x-request-id", "req-1")
+	response := httptest.NewRecorder()
+	s.Inject(response, req)
+
+	require.Equal(t, http.StatusNoContent, response.Code, "Status codes mismatch")
+	require.Equal(t, "user-1", received.Get("x-user-id"))
+	require.Equal(t, "true", received.Get("isbackoffice"))
+	require.Equal(t, "req-1", received.Get("x-request-id"))
+}
+
+// TestServiceTracing verifies that spans of incoming requests are named after plugin routes
+// and that their trace context is propagated by the service clients
+func TestServiceTracing(t *testing.T) {
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	s := NewService(ServiceOpts{
+		LogLevel: logLevel,
+		Tracing:  tracing.Opts{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))},
+	})
+
+	plugin := NewPlugin("/orders")
+	plugin.AddRoute("GET", "/{id}", func(rw http.ResponseWriter, r *http.Request) {
+		c := client.FromContext(r.Context()).New(upstream.URL)
+		req, err := c.NewRequest(r.Context(), http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		rw.WriteHeader(res.StatusCode)
+	})
+	s.Register(plugin)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/orders/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	s.Inject(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	clientSpan, serverSpan := spans[0], spans[1]
+	require.Equal(t, "/orders/{id}", serverSpan.Name)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverSpan.SpanContext.TraceID().String())
+	require.Equal(t, serverSpan.SpanContext.SpanID(), clientSpan.Parent.SpanID())
+	require.Contains(t, received.Get("traceparent"), clientSpan.SpanContext.SpanID().String())
+
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	recorder := httptest.NewRecorder()
+	s.Inject(recorder, req)
+	require.Contains(t, recorder.Body.String(), `# {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"}`)
+}
+
+// TestServiceTracingFlush verifies that the spans of the last served requests are exported
+// even when the shutdown hooks use up the whole grace period
+func TestServiceTracingFlush(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(time.Hour)))
+	s := NewService(ServiceOpts{
+		HTTPPort:            httpPort,
+		LogLevel:            logLevel,
+		ShutdownGracePeriod: 200 * time.Millisecond,
+		Tracing:             tracing.Opts{TracerProvider: tracerProvider},
+	})
+
+	plugin := NewPlugin("/orders")
+	plugin.AddRoute(http.MethodGet, "/{id}", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	plugin.OnShutdown(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	s.Register(plugin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, fmt.Sprintf("http://localhost:%d/orders/42", httpPort), nil)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Empty(t, exporter.GetSpans(), "spans are buffered until flushed")
+
+	cancel()
+	<-done
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "/orders/{id}", spans[0].Name)
+}
+
+// TestRequestID verifies that request ids are echoed in responses, reported in error bodies
+// and forwarded by the service clients
+func TestRequestID(t *testing.T) {
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	s := NewService(ServiceOpts{LogLevel: logLevel, RequestIDHeader: "x-correlation-id"})
+	plugin := NewPlugin("/")
+	plugin.AddRoute("GET", "/proxy", func(rw http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "corr-1", requestid.FromContext(r.Context()))
+
+		c := client.FromContext(r.Context()).New(upstream.URL)
+		req, err := c.NewRequest(r.Context(), http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		rw.WriteHeader(res.StatusCode)
+	})
+	s.Register(plugin)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/proxy", nil)
+	req.Header.Set("x-correlation-id", "corr-1")
+	rr := httptest.NewRecorder()
+	s.Inject(rr, req)
+
+	require.Equal(t, http.StatusNoContent, rr.Code, "Status codes mismatch")
+	require.Equal(t, "corr-1", rr.Header().Get("x-correlation-id"))
+	require.Equal(t, "corr-1", received.Get("x-correlation-id"))
+
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "/unknown", nil)
+	rr = httptest.NewRecorder()
+	s.Inject(rr, req)
+
+	require.Equal(t, http.StatusNotFound, rr.Code, "Status codes mismatch")
+	requestID := rr.Header().Get("x-correlation-id")
+	require.NotEmpty(t, requestID)
+	verifyJSONResponse(t, rr, map[string]interface{}{
+		"type":      "about:blank",
+		"title":     "Not Found",
+		"status":    float64(http.StatusNotFound),
+		"detail":    "Route not found",
+		"instance":  "/unknown",
+		"requestId": requestID,
+	})
+}
+
+// TestLegacyErrorFormat verifies that services can render errors with the legacy message and code body
+func TestLegacyErrorFormat(t *testing.T) {
+	s := NewService(ServiceOpts{LogLevel: logLevel, ErrorFormat: response.LegacyFormat})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/unknown", nil)
+	req.Header.Set("x-request-id", "req-1")
+	rr := httptest.NewRecorder()
+	s.Inject(rr, req)
+
+	require.Equal(t, http.StatusNotFound, rr.Code, "Status codes mismatch")
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	verifyJSONResponse(t, rr, map[string]interface{}{
+		"message":   "Route not found",
+		"code":      float64(http.StatusNotFound),
+		"requestId": "req-1",
+	})
+}
+
+// TestRequestMetricsRoute verifies that request metrics report
+// the full pattern of plugin routes, together with their plugin
+func TestRequestMetricsRoute(t *testing.T) {
+	s := NewService(ServiceOpts{LogLevel: logLevel, RequestMetrics: metrics.RequestMetricsOpts{PluginLabel: true}})
+
+	plugin := NewPlugin("/orders")
+	plugin.AddRoute("GET", "/{id}", func(rw http.ResponseWriter, r *http.Request) {})
+	s.Register(plugin)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/orders/42", nil)
+	s.Inject(httptest.NewRecorder(), req)
+
+	families, err := s.metricsRegistry.Gather()
+	require.NoError(t, err)
+
+	labels := make(map[string]string)
+	for _, family := range families {
+		if family.GetName() != "http_request_duration_seconds" {
+			continue
+		}
+		require.Len(t, family.GetMetric(), 1)
+		for _, label := range family.GetMetric()[0].GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+	}
+	require.Equal(t, map[string]string{"status": "200", "method": "GET", "route": "/orders/{id}", "plugin": "/orders"}, labels)
+}
+
+// TestRequestMetricsConstLabels verifies that request metrics report service name and version
+func TestRequestMetricsConstLabels(t *testing.T) {
+	s := NewService(ServiceOpts{
+		Name:           "orders",
+		Version:        "v1.2.3",
+		LogLevel:       logLevel,
+		RequestMetrics: metrics.RequestMetricsOpts{Namespace: "shop", ConstLabels: map[string]string{"team": "sales"}},
+	})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/healthz", nil)
+	s.Inject(httptest.NewRecorder(), req)
+
+	families, err := s.metricsRegistry.Gather()
+	require.NoError(t, err)
+
+	labels := make(map[string]string)
+	for _, family := range families {
+		if family.GetName() != "shop_http_request_duration_seconds" {
+			continue
+		}
+		for _, label := range family.GetMetric()[0].GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+	}
+	require.Equal(t, "orders", labels["service_name"])
+	require.Equal(t, "v1.2.3", labels["service_version"])
+	require.Equal(t, "sales", labels["team"])
+}
+
+// TestOpenMetricsExemplars verifies that the metrics route serves exemplars when OpenMetrics format is negotiated
+func TestOpenMetricsExemplars(t *testing.T) {
+	s := NewService(ServiceOpts{LogLevel: logLevel})
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/healthz", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	s.Inject(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	recorder := httptest.NewRecorder()
+	s.Inject(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Header().Get("Content-Type"), "application/openmetrics-text")
+	require.Contains(t, recorder.Body.String(), `# {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"}`)
+
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/metrics", nil)
+	recorder = httptest.NewRecorder()
+	s.Inject(recorder, req)
+	require.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+}
+
+// TestMultipleServices verifies that services in the same process own their request metrics
+// and that they can be injected repeatedly
+func TestMultipleServices(t *testing.T) {
+	newService := func() *Service {
+		s := NewService(ServiceOpts{LogLevel: logLevel})
+		plugin := NewPlugin("/")
+		plugin.AddRoute("GET", "/greet", func(rw http.ResponseWriter, r *http.Request) {})
+		s.Register(plugin)
+		return s
+	}
+	first, second := newService(), newService()
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/greet", nil)
+		response := httptest.NewRecorder()
+		require.NotPanics(t, func() { first.Inject(response, req) })
+		require.Equal(t, http.StatusOK, response.Code, "Status codes mismatch")
+	}
+
+	count := func(s *Service) uint64 {
+		families, err := s.metricsRegistry.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() == "http_request_duration_seconds" {
+				return family.GetMetric()[0].GetHistogram().GetSampleCount()
+			}
+		}
+		return 0
+	}
+	require.Equal(t, uint64(3), count(first))
+	require.Equal(t, uint64(0), count(second))
+}
+
+// TestServiceRun verifies that the service stops when its context is cancelled
+// and that errors are returned to the caller instead of terminating the process
+func TestServiceRun(t *testing.T) {
+	t.Run("Stop when context is cancelled", func(t *testing.T) {
+		s := NewService(ServiceOpts{HTTPPort: httpPort, LogLevel: logLevel})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			cancel()
+		}()
+
+		require.NoError(t, s.Run(ctx))
+	})
+
+	t.Run("Stop when Stop is called", func(t *testing.T) {
+		s := NewService(ServiceOpts{HTTPPort: httpPort, LogLevel: logLevel})
+
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			s.Stop()
+		}()
+
+		require.NoError(t, s.Run(context.Background()))
+	})
+
+	t.Run("Return listen error", func(t *testing.T) {
+		s := NewService(ServiceOpts{HTTPPort: -1, LogLevel: logLevel})
+
+		err := s.Run(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "server closed unexpectedly")
+	})
+}
+
+// TestServiceShutdownDrain verifies that during the drain phase the service
+// is reported as not ready, while it is still alive
+func TestServiceShutdownDrain(t *testing.T) {
+	s := NewService(ServiceOpts{
+		Name:                "test-service",
+		Version:             "v0.0.1",
+		HTTPPort:            httpPort,
+		LogLevel:            logLevel,
+		ShutdownDrainPeriod: 500 * time.Millisecond,
+		ShutdownGracePeriod: 5 * time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	response := requestStatusRoute(t, s, "ready")
+	require.Equal(t, http.StatusOK, response.Code, "service should be ready before shutdown")
+
+	cancel()
+	time.Sleep(200 * time.Millisecond)
+
+	t.Run("ready route reports service unavailable", func(t *testing.T) {
+		response := requestStatusRoute(t, s, "ready")
+
+		require.Equal(t, http.StatusServiceUnavailable, response.Code, "Status codes mismatch")
+		require.Equal(t, `{"name":"test-service","version":"v0.0.1","status":"KO"}`, strings.TrimSpace(response.Body.String()))
+	})
+
+	t.Run("healthz route still reports OK", func(t *testing.T) {
+		response := requestStatusRoute(t, s, "healthz")
+
+		require.Equal(t, http.StatusOK, response.Code, "Status codes mismatch")
+	})
+
+	require.NoError(t, <-done)
+}
+
+// TestServiceShutdownStreams verifies that streamed responses are stopped
+// when the shutdown starts, so that it does not wait for the grace period
+func TestServiceShutdownStreams(t *testing.T) {
+	s := NewService(ServiceOpts{HTTPPort: httpPort, LogLevel: logLevel, ShutdownGracePeriod: 10 * time.Second})
+
+	plugin := NewPlugin("/")
+	plugin.AddRoute(http.MethodGet, "/events", func(rw http.ResponseWriter, r *http.Request) {
+		events := make(chan response.Event, 1)
+		events <- response.Event{ID: "1", Data: "hello"}
+		_ = response.StreamSSE(rw, r, events, response.SSEOpts{})
+	})
+	s.Register(plugin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, fmt.Sprintf("http://localhost:%d/events", httpPort), nil)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, response.ContentTypeEventStream, res.Header.Get("Content-Type"))
+
+	start := time.Now()
+	cancel()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, "id: 1\ndata: hello\n\n", string(body))
+
+	require.NoError(t, <-done)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+// TestServiceHijack verifies that plugin handlers of a running service can take over the connection,
+// since the optional interfaces of the response writer are preserved by the service middlewares
+func TestServiceHijack(t *testing.T) {
+	s := NewService(ServiceOpts{HTTPPort: httpPort, LogLevel: logLevel})
+
+	plugin := NewPlugin("/")
+	plugin.AddRoute(http.MethodGet, "/ws", func(rw http.ResponseWriter, r *http.Request) {
+		_, readerFrom := rw.(io.ReaderFrom)
+		conn, buf, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nX-Reader-From: %t\r\n\r\n", readerFrom)
+		_ = buf.Flush()
+	})
+	s.Register(plugin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, fmt.Sprintf("http://localhost:%d/ws", httpPort), nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	require.Equal(t, http.StatusSwitchingProtocols, res.StatusCode, "Status codes mismatch")
+	require.Equal(t, "websocket", res.Header.Get("Upgrade"))
+	require.Equal(t, "true", res.Header.Get("X-Reader-From"), "sendfile is available to plugin handlers")
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// TestServiceStartupGate verifies that the service is reported as not started and not ready,
+// and that plugin routes are not served, while plugins start hooks are running
+func TestServiceStartupGate(t *testing.T) {
+	s := NewService(ServiceOpts{Name: "test-service", Version: "v0.0.1", HTTPPort: httpPort, LogLevel: logLevel})
+
+	warmUp := make(chan struct{})
+	plugin := NewPlugin("/")
+	plugin.OnStart(func(ctx context.Context) error {
+		<-warmUp
+		return nil
+	})
+	plugin.AddRoute(http.MethodGet, "/hello", func(rw http.ResponseWriter, r *http.Request) {
+		response.JSON(rw, map[string]string{"message": "hello"})
+	})
+	s.Register(plugin)
+	requestHello := func() int {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/hello", nil)
+		rr := httptest.NewRecorder()
+		s.Inject(rr, req)
+		return rr.Code
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+
+	require.Equal(t, http.StatusServiceUnavailable, requestStatusRoute(t, s, "startup").Code)
+	require.Equal(t, http.StatusServiceUnavailable, requestStatusRoute(t, s, "ready").Code)
+	require.Equal(t, http.StatusOK, requestStatusRoute(t, s, "healthz").Code)
+	require.Equal(t, http.StatusServiceUnavailable, requestHello(), "plugin routes are not served")
+
+	close(warmUp)
+	time.Sleep(100 * time.Millisecond)
+
+	require.Equal(t, http.StatusOK, requestStatusRoute(t, s, "startup").Code)
+	require.Equal(t, http.StatusOK, requestStatusRoute(t, s, "ready").Code)
+	require.Equal(t, http.StatusOK, requestHello())
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// requestStatusRoute calls in process a status route of the running service
+func requestStatusRoute(t *testing.T, s *Service, route string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/"+route, nil)
+	response := httptest.NewRecorder()
+	s.Inject(response, req)
+
+	return response
+}
+
 func executeRequest(t *testing.T, req *http.Request, s *Service) *httptest.ResponseRecorder {
 	t.Helper()
 
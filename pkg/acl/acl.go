@@ -0,0 +1,228 @@
+package acl
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/danibix95/miabase/pkg/platform"
+)
+
+// Expression is a parsed authorization rule, which is evaluated against
+// the platform user performing the request. Expressions are composed of:
+//
+//	groups.<name>                  true when the user belongs to the group
+//	isBackoffice                   true when the request comes from the backoffice
+//	clientType                     the client type of the request
+//	userId                         the identifier of the user
+//	user.properties.<path>         the value of a user property, nested fields are separated by dots
+//	'text', "text", 42, true       string, number and boolean literals
+//
+// combined with the operators ==, !=, !, && and || and grouped with parentheses.
+// Operators && and || treat empty, zero and missing values as false
+type Expression struct {
+	root node
+}
+
+// Parse converts the textual rule into an Expression, returning an error when it is not valid
+func Parse(rule string) (*Expression, error) {
+	tokens, err := tokenize(rule)
+	if err != nil {
+		return nil, fmt.Errorf("acl expression %q is not valid: %w", rule, err)
+	}
+
+	root, err := (&parser{tokens: tokens}).parse()
+	if err != nil {
+		return nil, fmt.Errorf("acl expression %q is not valid: %w", rule, err)
+	}
+
+	return &Expression{root: root}, nil
+}
+
+// MustParse is like Parse, but it panics when the rule is not valid
+func MustParse(rule string) *Expression {
+	expr, err := Parse(rule)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return expr
+}
+
+// And combines the provided expressions so that all of them must be satisfied.
+// Nil expressions are ignored, so that the result is nil when none is provided
+func And(exprs ...*Expression) *Expression {
+	var combined *Expression
+	for _, expr := range exprs {
+		switch {
+		case expr == nil:
+			continue
+		case combined == nil:
+			combined = expr
+		default:
+			combined = &Expression{root: binaryNode{op: opAnd, left: combined.root, right: expr.root}}
+		}
+	}
+
+	return combined
+}
+
+// Evaluate reports whether the user satisfies the expression
+func (e *Expression) Evaluate(user platform.PlatformUser) bool {
+	return truthy(e.root.eval(user))
+}
+
+// String returns the canonical representation of the expression
+func (e *Expression) String() string {
+	return e.root.String()
+}
+
+const (
+	opAnd = "&&"
+	opOr  = "||"
+	opEq  = "=="
+	opNeq = "!="
+	opNot = "!"
+)
+
+// precedence of binary operators, where higher values bind tighter
+var precedence = map[string]int{opOr: 1, opAnd: 2, opEq: 3, opNeq: 3}
+
+type node interface {
+	eval(user platform.PlatformUser) interface{}
+	String() string
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(platform.PlatformUser) interface{} { return n.value }
+
+func (n literalNode) String() string {
+	switch v := n.value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type groupNode struct {
+	name string
+}
+
+func (n groupNode) eval(user platform.PlatformUser) interface{} { return user.HasGroup(n.name) }
+
+func (n groupNode) String() string { return "groups." + n.name }
+
+type fieldNode struct {
+	name string
+}
+
+func (n fieldNode) eval(user platform.PlatformUser) interface{} {
+	switch n.name {
+	case "isBackoffice":
+		return user.IsBackOffice
+	case "clientType":
+		return user.ClientType
+	default:
+		return user.ID
+	}
+}
+
+func (n fieldNode) String() string { return n.name }
+
+type propertyNode struct {
+	path []string
+}
+
+func (n propertyNode) eval(user platform.PlatformUser) interface{} {
+	var value interface{} = user.Properties
+	for _, key := range n.path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	return value
+}
+
+func (n propertyNode) String() string { return "user.properties." + strings.Join(n.path, ".") }
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(user platform.PlatformUser) interface{} { return !truthy(n.operand.eval(user)) }
+
+func (n notNode) String() string {
+	if _, ok := n.operand.(binaryNode); ok {
+		return opNot + "(" + n.operand.String() + ")"
+	}
+
+	return opNot + n.operand.String()
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n binaryNode) eval(user platform.PlatformUser) interface{} {
+	switch n.op {
+	case opAnd:
+		return truthy(n.left.eval(user)) && truthy(n.right.eval(user))
+	case opOr:
+		return truthy(n.left.eval(user)) || truthy(n.right.eval(user))
+	case opEq:
+		return equal(n.left.eval(user), n.right.eval(user))
+	default:
+		return !equal(n.left.eval(user), n.right.eval(user))
+	}
+}
+
+func (n binaryNode) String() string {
+	return n.operandString(n.left, false) + " " + n.op + " " + n.operandString(n.right, true)
+}
+
+// operandString wraps the operand within parentheses when it binds looser than the operator
+func (n binaryNode) operandString(operand node, isRight bool) string {
+	child, ok := operand.(binaryNode)
+	if !ok {
+		return operand.String()
+	}
+
+	samePrecedence := precedence[child.op] == precedence[n.op]
+	// comparisons can not be chained, while the right operand is grouped to preserve evaluation order
+	if precedence[child.op] < precedence[n.op] || (samePrecedence && (isRight || precedence[n.op] == precedence[opEq])) {
+		return "(" + child.String() + ")"
+	}
+
+	return child.String()
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	default:
+		return true
+	}
+}
+
+func equal(left, right interface{}) bool {
+	return reflect.DeepEqual(left, right)
+}
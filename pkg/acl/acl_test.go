@@ -0,0 +1,135 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/platform"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	admin := platform.PlatformUser{ID: "user-1", Groups: []string{"admin"}}
+	support := platform.PlatformUser{ID: "user-2", Groups: []string{"support"}, IsBackOffice: true}
+	supportFromApp := platform.PlatformUser{ID: "user-3", Groups: []string{"support"}, ClientType: "mobile"}
+	customer := platform.PlatformUser{
+		ID:         "user-4",
+		ClientType: "mobile",
+		Properties: map[string]interface{}{
+			"tier":    "gold",
+			"orders":  float64(3),
+			"address": map[string]interface{}{"country": "IT"},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		expression string
+		allowed    []platform.PlatformUser
+		denied     []platform.PlatformUser
+	}{
+		{
+			name:       "groups combined with precedence",
+			expression: "groups.admin || groups.support && isBackoffice",
+			allowed:    []platform.PlatformUser{admin, support},
+			denied:     []platform.PlatformUser{supportFromApp, customer},
+		},
+		{
+			name:       "parentheses override precedence",
+			expression: "(groups.admin || groups.support) && !isBackoffice",
+			allowed:    []platform.PlatformUser{admin, supportFromApp},
+			denied:     []platform.PlatformUser{support, customer},
+		},
+		{
+			name:       "compare client type and user id",
+			expression: `clientType == 'mobile' && userId != "user-3"`,
+			allowed:    []platform.PlatformUser{customer},
+			denied:     []platform.PlatformUser{admin, supportFromApp},
+		},
+		{
+			name:       "compare user properties",
+			expression: "user.properties.tier == 'gold' && user.properties.orders == 3 && user.properties.address.country == 'IT'",
+			allowed:    []platform.PlatformUser{customer},
+			denied:     []platform.PlatformUser{admin},
+		},
+		{
+			name:       "missing properties are false",
+			expression: "user.properties.tier || user.properties.address.city.name",
+			allowed:    []platform.PlatformUser{customer},
+			denied:     []platform.PlatformUser{admin},
+		},
+		{
+			name:       "boolean literals",
+			expression: "true && !false",
+			allowed:    []platform.PlatformUser{admin, customer},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := Parse(tc.expression)
+			require.NoError(t, err)
+
+			for _, user := range tc.allowed {
+				require.True(t, expr.Evaluate(user), "user %s should be allowed", user.ID)
+			}
+			for _, user := range tc.denied {
+				require.False(t, expr.Evaluate(user), "user %s should be denied", user.ID)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("reject invalid expressions", func(t *testing.T) {
+		invalid := []string{
+			"",
+			"groups.",
+			"groups.admin ||",
+			"groups.admin & groups.support",
+			"(groups.admin",
+			"groups.admin)",
+			"unknown",
+			"clientType == 'mobile",
+			"user.properties.a..b",
+			"groups.admin groups.support",
+		}
+
+		for _, expression := range invalid {
+			_, err := Parse(expression)
+			require.Error(t, err, "expression %q should not be valid", expression)
+		}
+	})
+
+	t.Run("panic on invalid expressions", func(t *testing.T) {
+		require.Panics(t, func() { MustParse("groups.admin &&") })
+	})
+
+	t.Run("print canonical expression", func(t *testing.T) {
+		testCases := map[string]string{
+			"groups.admin||groups.support&&isBackoffice":       "groups.admin || groups.support && isBackoffice",
+			"(groups.admin || groups.support) && isBackoffice": "(groups.admin || groups.support) && isBackoffice",
+			"!(groups.admin && isBackoffice) || !groups.guest": "!(groups.admin && isBackoffice) || !groups.guest",
+			"clientType == 'mobile' && user.properties.n != 2": `clientType == "mobile" && user.properties.n != 2`,
+			"groups.a || (groups.b || groups.c)":               "groups.a || (groups.b || groups.c)",
+		}
+
+		for expression, expected := range testCases {
+			expr := MustParse(expression)
+			require.Equal(t, expected, expr.String())
+			// the canonical representation must be parsed into the same expression
+			require.Equal(t, expected, MustParse(expr.String()).String())
+		}
+	})
+}
+
+func TestAnd(t *testing.T) {
+	require.Nil(t, And(nil, nil))
+
+	single := MustParse("groups.admin")
+	require.Same(t, single, And(nil, single))
+
+	combined := And(MustParse("groups.admin || groups.support"), nil, MustParse("isBackoffice"))
+	require.Equal(t, "(groups.admin || groups.support) && isBackoffice", combined.String())
+	require.False(t, combined.Evaluate(platform.PlatformUser{Groups: []string{"admin"}}))
+	require.True(t, combined.Evaluate(platform.PlatformUser{Groups: []string{"admin"}, IsBackOffice: true}))
+}
@@ -0,0 +1,243 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenIdentifier tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenOpenParen
+	tokenCloseParen
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// tokenize splits the rule into identifiers, literals, operators and parentheses
+func tokenize(rule string) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(rule)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpenParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenCloseParen, value: ")", pos: i})
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: string(runes[i+1 : end]), pos: i})
+			i = end + 1
+		case r == '&' || r == '|' || r == '=' || r == '!':
+			if i+1 < len(runes) {
+				if op := string(runes[i : i+2]); op == opAnd || op == opOr || op == opEq || op == opNeq {
+					tokens = append(tokens, token{kind: tokenOperator, value: op, pos: i})
+					i += 2
+					continue
+				}
+			}
+			if r != '!' {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: opNot, pos: i})
+			i++
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[i:end]), pos: i})
+			i = end
+		case isIdentifierRune(r):
+			end := i
+			for end < len(runes) && (isIdentifierRune(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '.' || runes[end] == '-') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, value: string(runes[i:end]), pos: i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+		}
+	}
+
+	return tokens, nil
+}
+
+func isIdentifierRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '$'
+}
+
+// parser builds the expression tree through recursive descent, following the grammar
+//
+//	or      = and { "||" and }
+//	and     = compare { "&&" compare }
+//	compare = unary [ ( "==" | "!=" ) unary ]
+//	unary   = "!" unary | primary
+//	primary = "(" or ")" | identifier | literal
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) parse() (node, error) {
+	if len(p.tokens) == 0 {
+		return nil, errors.New("expression is empty")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		next := p.tokens[p.pos]
+		return nil, fmt.Errorf("unexpected %q at position %d", next.value, next.pos)
+	}
+
+	return root, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary(p.parseAnd, opOr)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseCompare, opAnd)
+}
+
+func (p *parser) parseBinary(operand func() (node, error), op string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(op) {
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{opEq, opNeq} {
+		if p.accept(op) {
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{op: op, left: left, right: right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept(opNot) {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	current := p.tokens[p.pos]
+	p.pos++
+
+	switch current.kind {
+	case tokenOpenParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenCloseParen {
+			return nil, fmt.Errorf("missing closing parenthesis for position %d", current.pos)
+		}
+		p.pos++
+		return inner, nil
+	case tokenString:
+		return literalNode{value: current.value}, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(current.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", current.value, current.pos)
+		}
+		return literalNode{value: value}, nil
+	case tokenIdentifier:
+		return identifier(current)
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", current.value, current.pos)
+	}
+}
+
+// identifier resolves the identifier into the user detail it refers to
+func identifier(t token) (node, error) {
+	switch {
+	case t.value == "true":
+		return literalNode{value: true}, nil
+	case t.value == "false":
+		return literalNode{value: false}, nil
+	case t.value == "isBackoffice", t.value == "clientType", t.value == "userId":
+		return fieldNode{name: t.value}, nil
+	case strings.HasPrefix(t.value, "groups.") && len(t.value) > len("groups."):
+		return groupNode{name: strings.TrimPrefix(t.value, "groups.")}, nil
+	case strings.HasPrefix(t.value, "user.properties.") && len(t.value) > len("user.properties."):
+		path := strings.Split(strings.TrimPrefix(t.value, "user.properties."), ".")
+		for _, key := range path {
+			if key == "" {
+				return nil, fmt.Errorf("invalid property %q at position %d", t.value, t.pos)
+			}
+		}
+		return propertyNode{path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown identifier %q at position %d", t.value, t.pos)
+	}
+}
+
+func (p *parser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].value == op {
+		p.pos++
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,255 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/go-chi/chi/v5"
+)
+
+// Locations of the request values bound to the input fields
+const (
+	InBody  = "body"
+	InPath  = "path"
+	InQuery = "query"
+
+	pathTag  = "path"
+	queryTag = "query"
+)
+
+// Error reports why the incoming request could not be bound to the input struct
+type Error struct {
+	Message string
+	Fields  []response.FieldError
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	reasons := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		reasons = append(reasons, fmt.Sprintf("%s %s", field.Field, field.Message))
+	}
+
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(reasons, ", "))
+}
+
+// Bind decodes the incoming request into the struct pointed by target and validates it.
+// The JSON body is decoded into the fields without path and query tags, then fields tagged with `path:"name"`
+// are read from chi route params and fields tagged with `query:"name"` from the querystring.
+// Finally, fields are checked against the rules expressed by their validate tag.
+// It returns an *Error when the request does not match the target struct.
+func Bind(r *http.Request, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		panic("binding target must be a pointer to a struct")
+	}
+
+	if err := decodeBody(r, target); err != nil {
+		return err
+	}
+
+	fieldErrors := bindParams(r, value.Elem())
+	if len(fieldErrors) > 0 {
+		return &Error{Message: "request parameters are not valid", Fields: fieldErrors}
+	}
+
+	if fieldErrors := Validate(target); len(fieldErrors) > 0 {
+		return &Error{Message: "request validation failed", Fields: fieldErrors}
+	}
+
+	return nil
+}
+
+// Check verifies, without any request, that the struct pointed by target can be bound:
+// path and query params must have a supported type and validate tags must hold valid rules.
+// It allows to report these mistakes when routes are registered rather than when requests arrive
+func Check(target interface{}) error {
+	targetType := reflect.TypeOf(target)
+	if targetType == nil || targetType.Kind() != reflect.Ptr || targetType.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binding target must be a pointer to a struct, not %T", target)
+	}
+
+	structType := targetType.Elem()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, in := fieldName(field)
+		if in != InBody && !paramTypeSupported(field.Type) {
+			return fmt.Errorf("%s param %s: type %s is not supported for path and query params", in, name, field.Type)
+		}
+	}
+
+	return checkRules(structType, make(map[reflect.Type]bool))
+}
+
+// paramTypeSupported reports whether setValue can convert the raw params into the type
+func paramTypeSupported(paramType reflect.Type) bool {
+	switch paramType.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return paramTypeSupported(paramType.Elem())
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// decodeBody decodes the JSON body into the target fields that are not read from path and query params,
+// so that clients can not set these fields through the body
+func decodeBody(r *http.Request, target interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	value := reflect.ValueOf(target).Elem()
+	decoded := reflect.New(value.Type())
+	decoded.Elem().Set(value)
+
+	err := json.NewDecoder(r.Body).Decode(decoded.Interface())
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		copyBodyFields(value, decoded.Elem())
+		return nil
+	default:
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return &Error{
+				Message: "request body is not valid",
+				Fields: []response.FieldError{{
+					Field:   typeErr.Field,
+					In:      InBody,
+					Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+				}},
+			}
+		}
+
+		return &Error{Message: fmt.Sprintf("request body is not valid: %s", err.Error())}
+	}
+}
+
+// copyBodyFields set the target fields that are read from the body to the decoded ones
+func copyBodyFields(target, decoded reflect.Value) {
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if _, in := fieldName(field); in == InBody {
+			target.Field(i).Set(decoded.Field(i))
+		}
+	}
+}
+
+// bindParams set the struct fields tagged as path or query params
+func bindParams(r *http.Request, value reflect.Value) []response.FieldError {
+	fieldErrors := make([]response.FieldError, 0)
+	query := r.URL.Query()
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name, found := field.Tag.Lookup(pathTag); found {
+			raw := chi.URLParam(r, name)
+			if raw == "" {
+				continue
+			}
+			if err := setValue(value.Field(i), []string{raw}); err != nil {
+				fieldErrors = append(fieldErrors, response.FieldError{Field: name, In: InPath, Message: err.Error()})
+			}
+		}
+
+		if name, found := field.Tag.Lookup(queryTag); found {
+			raw, found := query[name]
+			if !found {
+				continue
+			}
+			if err := setValue(value.Field(i), raw); err != nil {
+				fieldErrors = append(fieldErrors, response.FieldError{Field: name, In: InQuery, Message: err.Error()})
+			}
+		}
+	}
+
+	return fieldErrors
+}
+
+// setValue converts the raw values into the field type, which can be a primitive type,
+// a pointer to a primitive type or a slice of them
+func setValue(field reflect.Value, raw []string) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	case reflect.Slice:
+		values := reflect.MakeSlice(field.Type(), 0, len(raw))
+		for _, item := range raw {
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setValue(elem, []string{item}); err != nil {
+				return err
+			}
+			values = reflect.Append(values, elem)
+		}
+		field.Set(values)
+		return nil
+	default:
+		return setPrimitive(field, raw[len(raw)-1])
+	}
+}
+
+func setPrimitive(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be a positive integer")
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		field.SetFloat(v)
+	default:
+		panic(fmt.Sprintf("type %s is not supported for path and query params", field.Type()))
+	}
+
+	return nil
+}
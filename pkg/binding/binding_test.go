@@ -0,0 +1,182 @@
+package binding
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type orderInput struct {
+	ID       int      `json:"-" path:"id"`
+	Fields   []string `json:"-" query:"fields"`
+	Limit    *int     `json:"-" query:"limit" validate:"min=1,max=100"`
+	Customer string   `json:"customer" validate:"required"`
+	Total    float64  `json:"total"`
+}
+
+func TestBind(t *testing.T) {
+	t.Run("bind body, path and query params", func(t *testing.T) {
+		var input orderInput
+		req := newRequest(t, "/orders/42?fields=id&fields=total&limit=10", `{"customer":"mario","total":12.5}`, map[string]string{"id": "42"})
+
+		require.NoError(t, Bind(req, &input))
+		require.Equal(t, 42, input.ID)
+		require.Equal(t, []string{"id", "total"}, input.Fields)
+		require.Equal(t, 10, *input.Limit)
+		require.Equal(t, "mario", input.Customer)
+		require.Equal(t, 12.5, input.Total)
+	})
+
+	t.Run("report malformed body", func(t *testing.T) {
+		var input orderInput
+		req := newRequest(t, "/orders/42", `{"customer":`, nil)
+
+		err := Bind(req, &input)
+
+		var bindErr *Error
+		require.True(t, errors.As(err, &bindErr))
+		require.Contains(t, bindErr.Message, "request body is not valid")
+	})
+
+	t.Run("report body fields with wrong type", func(t *testing.T) {
+		var input orderInput
+		req := newRequest(t, "/orders/42", `{"customer":"mario","total":"many"}`, nil)
+
+		err := Bind(req, &input)
+
+		var bindErr *Error
+		require.True(t, errors.As(err, &bindErr))
+		require.Equal(t, []response.FieldError{{Field: "total", In: InBody, Message: "must be of type float64"}}, bindErr.Fields)
+	})
+
+	t.Run("report params that can not be converted", func(t *testing.T) {
+		var input orderInput
+		req := newRequest(t, "/orders/abc?limit=ten", `{"customer":"mario"}`, map[string]string{"id": "abc"})
+
+		err := Bind(req, &input)
+
+		var bindErr *Error
+		require.True(t, errors.As(err, &bindErr))
+		require.Equal(t, []response.FieldError{
+			{Field: "id", In: InPath, Message: "must be an integer"},
+			{Field: "limit", In: InQuery, Message: "must be an integer"},
+		}, bindErr.Fields)
+	})
+
+	t.Run("report validation errors", func(t *testing.T) {
+		var input orderInput
+		req := newRequest(t, "/orders/42?limit=500", "", map[string]string{"id": "42"})
+
+		err := Bind(req, &input)
+
+		var bindErr *Error
+		require.True(t, errors.As(err, &bindErr))
+		require.Equal(t, "request validation failed", bindErr.Message)
+		require.Equal(t, []response.FieldError{
+			{Field: "limit", In: InQuery, Message: "must be lower than or equal to 100"},
+			{Field: "customer", In: InBody, Message: "is required"},
+		}, bindErr.Fields)
+		require.Equal(t, "request validation failed: limit must be lower than or equal to 100, customer is required", err.Error())
+	})
+
+	t.Run("ignore path and query params sent through the body", func(t *testing.T) {
+		type input struct {
+			ID       int    `path:"id"`
+			Owner    string `query:"owner"`
+			Customer string `json:"customer"`
+		}
+		var target input
+		req := newRequest(t, "/orders", `{"ID":42,"Owner":"admin","customer":"mario"}`, nil)
+
+		require.NoError(t, Bind(req, &target))
+		require.Equal(t, input{Customer: "mario"}, target)
+	})
+
+	t.Run("panic when target is not a pointer to struct", func(t *testing.T) {
+		req := newRequest(t, "/", "", nil)
+
+		require.Panics(t, func() {
+			var input orderInput
+			_ = Bind(req, input)
+		})
+	})
+}
+
+func newRequest(t *testing.T, target, body string, params map[string]string) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if body == "" {
+		req.Body = http.NoBody
+	}
+
+	routeCtx := chi.NewRouteContext()
+	for key, value := range params {
+		routeCtx.URLParams.Add(key, value)
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+}
+
+func TestCheck(t *testing.T) {
+	type address struct {
+		City string `json:"city" validate:"min=abc"`
+	}
+
+	testCases := []struct {
+		name   string
+		target interface{}
+		err    string
+	}{
+		{name: "bindable struct", target: &orderInput{}},
+		{name: "not a pointer", target: orderInput{}, err: "binding target must be a pointer to a struct, not binding.orderInput"},
+		{name: "not a struct", target: new(string), err: "binding target must be a pointer to a struct, not *string"},
+		{name: "nil target", target: nil, err: "binding target must be a pointer to a struct, not <nil>"},
+		{
+			name: "unsupported param type",
+			target: &struct {
+				Filter map[string]string `query:"filter"`
+			}{},
+			err: "query param filter: type map[string]string is not supported for path and query params",
+		},
+		{
+			name: "unsupported rule",
+			target: &struct {
+				Name string `json:"name" validate:"email"`
+			}{},
+			err: "field name: validation rule email is not supported",
+		},
+		{
+			name: "rule not supported by the field type",
+			target: &struct {
+				Enabled *bool `json:"enabled" validate:"max=1"`
+			}{},
+			err: "field enabled: validation rule max is not supported for type bool",
+		},
+		{
+			name: "invalid rule argument in nested structs",
+			target: &struct {
+				Addresses []*address `json:"addresses"`
+			}{},
+			err: "field city: validation rule min=abc has an invalid argument",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Check(tc.target)
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
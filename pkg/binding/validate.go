@@ -0,0 +1,262 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/danibix95/miabase/pkg/response"
+)
+
+const validateTag = "validate"
+
+// Validate checks the struct pointed by target against the rules expressed by its fields validate tag,
+// returning the list of fields that do not respect them. Rules are separated by commas:
+//
+//	required    the field must not be the zero value of its type
+//	min=N       numbers must be greater than or equal to N, while strings, slices and maps must have at least N elements
+//	max=N       numbers must be lower than or equal to N, while strings, slices and maps must have at most N elements
+//	oneof=A B   the field must be equal to one of the space separated values
+//
+// Nested structs and slices of structs are validated as well.
+func Validate(target interface{}) []response.FieldError {
+	value := reflect.ValueOf(target)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		panic("validation target must be a struct")
+	}
+
+	return validateStruct(value, "")
+}
+
+func validateStruct(value reflect.Value, prefix string) []response.FieldError {
+	fieldErrors := make([]response.FieldError, 0)
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, in := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		if in == InBody {
+			name = prefix + name
+		}
+
+		fieldValue := value.Field(i)
+		for _, rule := range splitRules(field.Tag.Get(validateTag)) {
+			if message := checkRule(fieldValue, rule); message != "" {
+				fieldErrors = append(fieldErrors, response.FieldError{Field: name, In: in, Message: message})
+				// further rules are not meaningful once one of them fails
+				break
+			}
+		}
+
+		fieldErrors = append(fieldErrors, validateNested(fieldValue, name)...)
+	}
+
+	return fieldErrors
+}
+
+// validateNested validates the structs contained in the field value
+func validateNested(value reflect.Value, name string) []response.FieldError {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return validateStruct(value, name+".")
+	case reflect.Slice, reflect.Array:
+		fieldErrors := make([]response.FieldError, 0)
+		for i := 0; i < value.Len(); i++ {
+			fieldErrors = append(fieldErrors, validateNested(value.Index(i), fmt.Sprintf("%s[%d]", name, i))...)
+		}
+		return fieldErrors
+	default:
+		return nil
+	}
+}
+
+// fieldName returns the name of the field as it appears in the request and where it is located
+func fieldName(field reflect.StructField) (string, string) {
+	if name, found := field.Tag.Lookup(pathTag); found {
+		return name, InPath
+	}
+	if name, found := field.Tag.Lookup(queryTag); found {
+		return name, InQuery
+	}
+
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, InBody
+}
+
+func splitRules(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+
+	return strings.Split(tag, ",")
+}
+
+// checkRule returns the reason why the value does not respect the rule, or an empty string
+func checkRule(value reflect.Value, rule string) string {
+	name, arg := rule, ""
+	if idx := strings.Index(rule, "="); idx >= 0 {
+		name, arg = rule[:idx], rule[idx+1:]
+	}
+
+	if name == "required" {
+		if value.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+
+	// other rules apply only to values that have been provided
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validation rule %s has an invalid argument", rule))
+		}
+		return checkLimit(value, name, limit)
+	case "oneof":
+		actual := fmt.Sprintf("%v", value.Interface())
+		for _, allowed := range strings.Fields(arg) {
+			if actual == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s]", arg)
+	default:
+		panic(fmt.Sprintf("validation rule %s is not supported", name))
+	}
+}
+
+func checkLimit(value reflect.Value, rule string, limit float64) string {
+	var size float64
+	isLength := true
+
+	switch value.Kind() {
+	case reflect.String:
+		size = float64(utf8.RuneCountInString(value.String()))
+	case reflect.Slice, reflect.Array, reflect.Map:
+		size = float64(value.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size, isLength = float64(value.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size, isLength = float64(value.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		size, isLength = value.Float(), false
+	default:
+		panic(fmt.Sprintf("validation rule %s is not supported for type %s", rule, value.Type()))
+	}
+
+	limitText := strconv.FormatFloat(limit, 'f', -1, 64)
+	switch {
+	case rule == "min" && size < limit && isLength:
+		return fmt.Sprintf("must contain at least %s elements", limitText)
+	case rule == "min" && size < limit:
+		return fmt.Sprintf("must be greater than or equal to %s", limitText)
+	case rule == "max" && size > limit && isLength:
+		return fmt.Sprintf("must contain at most %s elements", limitText)
+	case rule == "max" && size > limit:
+		return fmt.Sprintf("must be lower than or equal to %s", limitText)
+	default:
+		return ""
+	}
+}
+
+// checkRules verifies that the validate tags of the struct fields, including the nested ones,
+// hold supported rules with valid arguments
+func checkRules(structType reflect.Type, visited map[reflect.Type]bool) error {
+	if visited[structType] {
+		return nil
+	}
+	visited[structType] = true
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, _ := fieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		for _, rule := range splitRules(field.Tag.Get(validateTag)) {
+			if err := checkRuleDefinition(field.Type, rule); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+		}
+
+		nestedType := field.Type
+		for nestedType.Kind() == reflect.Ptr || nestedType.Kind() == reflect.Slice || nestedType.Kind() == reflect.Array {
+			nestedType = nestedType.Elem()
+		}
+		if nestedType.Kind() == reflect.Struct {
+			if err := checkRules(nestedType, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRuleDefinition returns why the rule can not be applied to values of the field type
+func checkRuleDefinition(fieldType reflect.Type, rule string) error {
+	name, arg := rule, ""
+	if idx := strings.Index(rule, "="); idx >= 0 {
+		name, arg = rule[:idx], rule[idx+1:]
+	}
+
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch name {
+	case "required", "oneof":
+		return nil
+	case "min", "max":
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return fmt.Errorf("validation rule %s has an invalid argument", rule)
+		}
+		switch fieldType.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return nil
+		default:
+			return fmt.Errorf("validation rule %s is not supported for type %s", name, fieldType)
+		}
+	default:
+		return fmt.Errorf("validation rule %s is not supported", name)
+	}
+}
@@ -0,0 +1,68 @@
+package binding
+
+import (
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	SKU      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
+
+type cart struct {
+	Owner    string   `json:"owner" validate:"required,min=3,max=10"`
+	Currency string   `json:"currency,omitempty" validate:"oneof=EUR USD"`
+	Items    []item   `json:"items" validate:"min=1"`
+	Discount *float64 `json:"discount,omitempty" validate:"min=0,max=50"`
+	Shipping *item    `json:"shipping,omitempty"`
+	Notes    string   `json:"-"`
+}
+
+func TestValidate(t *testing.T) {
+	discount := 10.0
+
+	t.Run("valid struct", func(t *testing.T) {
+		c := cart{Owner: "mario", Currency: "EUR", Items: []item{{SKU: "A1", Quantity: 1}}, Discount: &discount}
+
+		require.Empty(t, Validate(&c))
+		require.Empty(t, Validate(c), "struct values can be validated too")
+	})
+
+	t.Run("report each invalid field once", func(t *testing.T) {
+		tooHigh := 80.0
+		c := cart{Owner: "al", Currency: "GBP", Discount: &tooHigh}
+
+		require.Equal(t, []response.FieldError{
+			{Field: "owner", In: InBody, Message: "must contain at least 3 elements"},
+			{Field: "currency", In: InBody, Message: "must be one of [EUR USD]"},
+			{Field: "items", In: InBody, Message: "must contain at least 1 elements"},
+			{Field: "discount", In: InBody, Message: "must be lower than or equal to 50"},
+		}, Validate(&c))
+	})
+
+	t.Run("validate nested structs", func(t *testing.T) {
+		c := cart{
+			Owner:    "mario",
+			Currency: "USD",
+			Items:    []item{{SKU: "A1", Quantity: 1}, {Quantity: 0}},
+			Shipping: &item{SKU: "SHIP"},
+		}
+
+		require.Equal(t, []response.FieldError{
+			{Field: "items[1].sku", In: InBody, Message: "is required"},
+			{Field: "items[1].quantity", In: InBody, Message: "must be greater than or equal to 1"},
+			{Field: "shipping.quantity", In: InBody, Message: "must be greater than or equal to 1"},
+		}, Validate(&c))
+	})
+
+	t.Run("panic on unknown rules", func(t *testing.T) {
+		type invalid struct {
+			Name string `validate:"email"`
+		}
+
+		require.Panics(t, func() { Validate(&invalid{Name: "mario"}) })
+	})
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danibix95/miabase/pkg/requestid"
+	"github.com/danibix95/miabase/pkg/tracing"
+	zpstd "github.com/danibix95/zeropino/middlewares/std"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultTimeout is the maximum duration of the requests performed by clients
+// when no timeout is configured
+const DefaultTimeout = 10 * time.Second
+
+// RequestIDHeader is the default header carrying the identifier of the request, which is forwarded to other services
+const RequestIDHeader = requestid.DefaultHeader
+
+const (
+	statusLabel = "status"
+	methodLabel = "method"
+	hostLabel   = "host"
+
+	// errorStatus is the status label of requests that did not receive a response
+	errorStatus = "error"
+)
+
+type contextKey struct{}
+
+// Metrics collects the duration of the requests that clients perform towards other services
+type Metrics struct {
+	requestDuration *prometheus.HistogramVec
+}
+
+// NewMetrics register the outgoing requests metrics employing the provided prometheus factory
+func NewMetrics(pf promauto.Factory) *Metrics {
+	return &Metrics{
+		requestDuration: pf.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_client_request_duration_seconds",
+				Help:    "outgoing request duration in seconds",
+				Buckets: []float64{0.05, 0.1, 0.5, 1, 3, 5, 10},
+			},
+			[]string{statusLabel, methodLabel, hostLabel},
+		),
+	}
+}
+
+func (m *Metrics) observe(status, method, host string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.requestDuration.WithLabelValues(status, method, host).Observe(duration.Seconds())
+}
+
+// Config defines how the clients created while handling incoming requests behave
+type Config struct {
+	// Headers lists the names of the incoming request headers that are forwarded to other services
+	Headers []string
+	// Timeout is the maximum duration of each outgoing request. Defaults to DefaultTimeout
+	Timeout time.Duration
+	// Metrics collects the outgoing requests duration. No metric is recorded when it is nil
+	Metrics *Metrics
+	// Transport performs the outgoing requests. Defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Tracing creates a client span for each outgoing request, propagating its trace context
+	// to the called service. No span is created when its tracer provider is nil
+	Tracing tracing.Opts
+}
+
+// Factory creates the clients employed to call other services while handling an incoming request
+type Factory struct {
+	forward   http.Header
+	timeout   time.Duration
+	metrics   *Metrics
+	transport http.RoundTripper
+	logger    *zerolog.Logger
+	tracing   tracing.Opts
+}
+
+// Middleware returns an http middleware that stores in each request context a Factory,
+// whose clients forward the configured headers of the incoming request
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			forward := make(http.Header, len(cfg.Headers))
+			for _, key := range cfg.Headers {
+				if values := r.Header.Values(key); len(values) > 0 {
+					forward[http.CanonicalHeaderKey(key)] = values
+				}
+			}
+
+			f := newFactory(cfg, forward, zpstd.Get(r.Context()))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, f)))
+		})
+	}
+}
+
+// FromContext returns the Factory stored in the context of the incoming request.
+// When the context does not hold any factory, the returned one creates clients
+// that do not forward any header and do not record metrics
+func FromContext(ctx context.Context) *Factory {
+	if f, ok := ctx.Value(contextKey{}).(*Factory); ok {
+		return f
+	}
+
+	return newFactory(Config{}, http.Header{}, zpstd.Get(ctx))
+}
+
+func newFactory(cfg Config, forward http.Header, logger *zerolog.Logger) *Factory {
+	f := &Factory{
+		forward:   forward,
+		timeout:   cfg.Timeout,
+		metrics:   cfg.Metrics,
+		transport: cfg.Transport,
+		logger:    logger,
+		tracing:   cfg.Tracing.WithDefaults(),
+	}
+	if f.timeout <= 0 {
+		f.timeout = DefaultTimeout
+	}
+	if f.transport == nil {
+		f.transport = http.DefaultTransport
+	}
+
+	return f
+}
+
+// Options customizes a single client
+type Options struct {
+	// Timeout overrides the maximum duration of each request performed by the client
+	Timeout time.Duration
+	// Headers are added to each request performed by the client
+	Headers http.Header
+}
+
+// Client performs requests towards a service, forwarding the headers of the incoming request
+type Client struct {
+	baseURL    string
+	headers    http.Header
+	httpClient *http.Client
+}
+
+// New creates a client that performs requests towards the service reachable at baseURL
+func (f *Factory) New(baseURL string, opts ...Options) *Client {
+	var opt Options
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	timeout := f.timeout
+	if opt.Timeout > 0 {
+		timeout = opt.Timeout
+	}
+
+	headers := f.forward.Clone()
+	for key, values := range opt.Headers {
+		headers[http.CanonicalHeaderKey(key)] = values
+	}
+
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &transport{
+				next:       f.transport,
+				headers:    headers,
+				metrics:    f.metrics,
+				logger:     f.logger,
+				tracer:     f.tracing.Tracer(),
+				propagator: f.tracing.Propagator,
+			},
+		},
+	}
+}
+
+// NewRequest creates a request towards the path of the client service
+func (c *Client) NewRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	url := c.baseURL + "/" + strings.TrimPrefix(path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s can not be created: %w", url, err)
+	}
+
+	return req, nil
+}
+
+// Do performs the request, adding the forwarded headers to it
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req)
+}
+
+// HTTPClient returns the underlying http client,
+// which forwards headers and records metrics as well
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// transport decorates the outgoing requests with the forwarded headers and the trace context,
+// recording their duration and logging their outcome
+type transport struct {
+	next       http.RoundTripper
+	headers    http.Header
+	metrics    *Metrics
+	logger     *zerolog.Logger
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// requests must not be modified by round trippers
+	req = req.Clone(req.Context())
+	for key, values := range t.headers {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+
+	// without tracer, a non recording span leaves the span of the incoming request untouched
+	span := trace.SpanFromContext(context.Background())
+	if t.tracer != nil {
+		var ctx context.Context
+		ctx, span = t.tracer.Start(req.Context(), fmt.Sprintf("HTTP %s", req.Method),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(req.Method),
+				semconv.HTTPURLKey.String(req.URL.Redacted()),
+				semconv.NetPeerNameKey.String(req.URL.Hostname()),
+			),
+		)
+		defer span.End()
+
+		req = req.WithContext(ctx)
+		t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	duration := time.Since(start)
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		t.metrics.observe(errorStatus, req.Method, req.URL.Host, duration)
+		t.logger.Error().
+			Err(err).
+			Str("method", req.Method).
+			Str("url", req.URL.Redacted()).
+			Float64("responseTime", float64(duration.Milliseconds())).
+			Msg("outgoing request failed")
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(res.StatusCode))
+	if res.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+	}
+	t.metrics.observe(strconv.Itoa(res.StatusCode), req.Method, req.URL.Host, duration)
+	t.logger.Debug().
+		Str("method", req.Method).
+		Str("url", req.URL.Redacted()).
+		Int("statusCode", res.StatusCode).
+		Float64("responseTime", float64(duration.Milliseconds())).
+		Msg("outgoing request completed")
+
+	return res, nil
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danibix95/miabase/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestClient(t *testing.T) {
+	// factoryFor runs the middleware on the incoming request, returning the factory stored in its context
+	factoryFor := func(cfg Config, incoming *http.Request) *Factory {
+		var factory *Factory
+		Middleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			factory = FromContext(r.Context())
+		})).ServeHTTP(httptest.NewRecorder(), incoming)
+		return factory
+	}
+
+	t.Run("forward configured headers of the incoming request", func(t *testing.T) {
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			require.Equal(t, "/orders/42", r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		incoming := httptest.NewRequest(http.MethodGet, "/", nil)
+		incoming.Header.Set("miauserid", "user-1")
+		incoming.Header.Set(RequestIDHeader, "req-1")
+		incoming.Header.Set("authorization", "secret")
+
+		factory := factoryFor(Config{Headers: []string{"miauserid", "isbackoffice", RequestIDHeader}}, incoming)
+		c := factory.New(server.URL+"/", Options{Headers: http.Header{"X-Custom": []string{"value"}}})
+
+		req, err := c.NewRequest(context.Background(), http.MethodGet, "/orders/42", nil)
+		require.NoError(t, err)
+		req.Header.Set("miauserid", "user-2")
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+		// headers set on the outgoing request take precedence over forwarded ones
+		require.Equal(t, "user-2", received.Get("miauserid"))
+		require.Equal(t, "req-1", received.Get(RequestIDHeader))
+		require.Equal(t, "value", received.Get("x-custom"))
+		require.Empty(t, received.Get("authorization"))
+		require.Empty(t, received.Values("isbackoffice"))
+		// the original request is not modified
+		require.Empty(t, req.Header.Get(RequestIDHeader))
+	})
+
+	t.Run("record outgoing requests metrics", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		reg := prometheus.NewRegistry()
+		factory := factoryFor(Config{Metrics: NewMetrics(promauto.With(reg))}, httptest.NewRequest(http.MethodGet, "/", nil))
+		c := factory.New(server.URL)
+
+		req, err := c.NewRequest(context.Background(), http.MethodPost, "items", strings.NewReader("{}"))
+		require.NoError(t, err)
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+
+		count, err := testutil.GatherAndCount(reg, "http_client_request_duration_seconds")
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		families, err := reg.Gather()
+		require.NoError(t, err)
+		labels := make(map[string]string)
+		for _, label := range families[0].GetMetric()[0].GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		require.Equal(t, map[string]string{
+			statusLabel: "404",
+			methodLabel: http.MethodPost,
+			hostLabel:   strings.TrimPrefix(server.URL, "http://"),
+		}, labels)
+	})
+
+	t.Run("apply timeouts", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer server.Close()
+
+		factory := factoryFor(Config{Timeout: time.Minute}, httptest.NewRequest(http.MethodGet, "/", nil))
+		c := factory.New(server.URL, Options{Timeout: 50 * time.Millisecond})
+		require.Equal(t, 50*time.Millisecond, c.HTTPClient().Timeout)
+
+		req, err := c.NewRequest(context.Background(), http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		_, err = c.Do(req)
+
+		var netErr interface{ Timeout() bool }
+		require.True(t, errors.As(err, &netErr) && netErr.Timeout(), "request should time out")
+	})
+
+	t.Run("provide a default factory when the context does not hold one", func(t *testing.T) {
+		c := FromContext(context.Background()).New("http://localhost")
+
+		require.Equal(t, DefaultTimeout, c.HTTPClient().Timeout)
+		require.Empty(t, c.headers)
+	})
+
+	t.Run("create client spans propagating the trace context", func(t *testing.T) {
+		var received http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Clone()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		exporter := tracetest.NewInMemoryExporter()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		ctx, parent := tp.Tracer("test").Start(context.Background(), "incoming")
+		defer parent.End()
+
+		factory := factoryFor(Config{Tracing: tracing.Opts{TracerProvider: tp}}, httptest.NewRequest(http.MethodGet, "/", nil))
+		c := factory.New(server.URL)
+		req, err := c.NewRequest(ctx, http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "HTTP GET", spans[0].Name)
+		require.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+		require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+		require.Equal(t, codes.Error, spans[0].Status.Code)
+		require.Equal(t, fmt.Sprintf("00-%s-%s-01", spans[0].SpanContext.TraceID(), spans[0].SpanContext.SpanID()), received.Get("traceparent"))
+		require.Empty(t, req.Header.Get("traceparent"), "original request is not modified")
+	})
+}
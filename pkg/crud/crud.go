@@ -0,0 +1,251 @@
+package crud
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/danibix95/miabase/pkg/client"
+)
+
+// Client reads and writes the documents of a CRUD Service collection, decoding them into T
+type Client[T any] struct {
+	client *client.Client
+}
+
+// New creates a client for the collection served by the provided client,
+// whose base URL must point to the collection route, such as http://crud-service/books
+func New[T any](c *client.Client) *Client[T] {
+	return &Client[T]{client: c}
+}
+
+// FromContext creates a client for the collection reachable at collectionURL,
+// which forwards the platform headers of the incoming request whose context is provided
+func FromContext[T any](ctx context.Context, collectionURL string, opts ...client.Options) *Client[T] {
+	return New[T](client.FromContext(ctx).New(collectionURL, opts...))
+}
+
+type createdDocument struct {
+	ID string `json:"_id"`
+}
+
+// List returns the documents selected by the query
+func (c *Client[T]) List(ctx context.Context, q Query) ([]T, error) {
+	documents := make([]T, 0)
+	if err := c.do(ctx, http.MethodGet, "/", q, nil, &documents); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+// Get returns the document with the given id, applying the query states and projection.
+// It returns an error matching ErrNotFound when the document does not exist
+func (c *Client[T]) Get(ctx context.Context, id string, q Query) (T, error) {
+	var document T
+	err := c.do(ctx, http.MethodGet, "/"+url.PathEscape(id), q, nil, &document)
+
+	return document, err
+}
+
+// Count returns the number of documents selected by the query
+func (c *Client[T]) Count(ctx context.Context, q Query) (int, error) {
+	var count int
+	err := c.do(ctx, http.MethodGet, "/count", q, nil, &count)
+
+	return count, err
+}
+
+// Export streams the documents selected by the query, calling fn for each of them.
+// The export is interrupted by the first error returned by fn
+func (c *Client[T]) Export(ctx context.Context, q Query, fn func(T) error) error {
+	res, err := c.send(ctx, http.MethodGet, "/export", q, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	scanner := bufio.NewScanner(res.Body)
+	// exported documents can exceed the default token size
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var document T
+		if err := json.Unmarshal(line, &document); err != nil {
+			return fmt.Errorf("exported document can not be decoded: %w", err)
+		}
+		if err := fn(document); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Paginate calls fn with each page of the given size of the documents selected by the query,
+// until the last page is reached or fn returns an error
+func (c *Client[T]) Paginate(ctx context.Context, q Query, size int, fn func(page []T) error) error {
+	if size <= 0 {
+		return fmt.Errorf("page size must be positive, got %d", size)
+	}
+
+	for page := 1; ; page++ {
+		documents, err := c.List(ctx, q.Page(page, size))
+		if err != nil {
+			return err
+		}
+		if len(documents) > 0 {
+			if err := fn(documents); err != nil {
+				return err
+			}
+		}
+		if len(documents) < size {
+			return nil
+		}
+	}
+}
+
+// Create inserts the document, returning its id
+func (c *Client[T]) Create(ctx context.Context, document T) (string, error) {
+	var created createdDocument
+	err := c.do(ctx, http.MethodPost, "/", NewQuery(), document, &created)
+
+	return created.ID, err
+}
+
+// CreateMany inserts the documents, returning their ids in the same order
+func (c *Client[T]) CreateMany(ctx context.Context, documents []T) ([]string, error) {
+	created := make([]createdDocument, 0, len(documents))
+	if err := c.do(ctx, http.MethodPost, "/bulk", NewQuery(), documents, &created); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(created))
+	for _, document := range created {
+		ids = append(ids, document.ID)
+	}
+
+	return ids, nil
+}
+
+// Patch applies the update to the document with the given id, returning the updated document.
+// The query can restrict the update to documents in given states or matching a filter
+func (c *Client[T]) Patch(ctx context.Context, id string, update Update, q Query) (T, error) {
+	var document T
+	err := c.do(ctx, http.MethodPatch, "/"+url.PathEscape(id), q, update, &document)
+
+	return document, err
+}
+
+// PatchMany applies the update to the documents selected by the query, returning how many were updated
+func (c *Client[T]) PatchMany(ctx context.Context, q Query, update Update) (int, error) {
+	var count int
+	err := c.do(ctx, http.MethodPatch, "/", q, update, &count)
+
+	return count, err
+}
+
+// PatchBulk applies each operation to the documents matching its filter, returning how many were updated
+func (c *Client[T]) PatchBulk(ctx context.Context, operations []PatchOperation) (int, error) {
+	var count int
+	err := c.do(ctx, http.MethodPatch, "/bulk", NewQuery(), operations, &count)
+
+	return count, err
+}
+
+// Delete removes the document with the given id
+func (c *Client[T]) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/"+url.PathEscape(id), NewQuery(), nil, nil)
+}
+
+// DeleteMany removes the documents selected by the query, returning how many were removed
+func (c *Client[T]) DeleteMany(ctx context.Context, q Query) (int, error) {
+	var count int
+	err := c.do(ctx, http.MethodDelete, "/", q, nil, &count)
+
+	return count, err
+}
+
+// SetState moves the document with the given id to the provided state
+func (c *Client[T]) SetState(ctx context.Context, id string, state State) error {
+	body := map[string]State{"stateTo": state}
+	return c.do(ctx, http.MethodPost, "/"+url.PathEscape(id)+"/state", NewQuery(), body, nil)
+}
+
+// SetStates applies the state transitions, returning how many documents changed their state
+func (c *Client[T]) SetStates(ctx context.Context, transitions []StateTransition) (int, error) {
+	var count int
+	err := c.do(ctx, http.MethodPost, "/state", NewQuery(), transitions, &count)
+
+	return count, err
+}
+
+// do performs the request, decoding the response body into target when provided
+func (c *Client[T]) do(ctx context.Context, method, path string, q Query, body, target interface{}) error {
+	res, err := c.send(ctx, method, path, q, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if target == nil {
+		// drain the body to reuse the connection
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(target); err != nil {
+		return fmt.Errorf("crud service response can not be decoded: %w", err)
+	}
+
+	return nil
+}
+
+// send performs the request, returning an *Error when the response status is not successful
+func (c *Client[T]) send(ctx context.Context, method, path string, q Query, body interface{}) (*http.Response, error) {
+	values, err := q.Values()
+	if err != nil {
+		return nil, fmt.Errorf("crud query can not be encoded: %w", err)
+	}
+	if encoded := values.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("crud request body can not be encoded: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := c.client.NewRequest(ctx, method, path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("crud service can not be reached: %w", err)
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		defer res.Body.Close()
+		return nil, responseError(res)
+	}
+
+	return res, nil
+}
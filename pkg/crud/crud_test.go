@@ -0,0 +1,253 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+type book struct {
+	ID     string `json:"_id,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Author string `json:"author,omitempty"`
+	Pages  int    `json:"pages,omitempty"`
+	State  State  `json:"__STATE__,omitempty"`
+}
+
+func newTestClient(t *testing.T) (*Client[book], *fakeCRUD) {
+	t.Helper()
+
+	fake := newFakeCRUD()
+	server := httptest.NewServer(http.StripPrefix("/books", fake))
+	t.Cleanup(server.Close)
+
+	return FromContext[book](context.Background(), server.URL+"/books"), fake
+}
+
+func TestQuery(t *testing.T) {
+	t.Run("encode query into querystring parameters", func(t *testing.T) {
+		base := NewQuery().Where("author", "Calvino")
+		q := base.
+			Where("pages", 300).
+			States(StatePublic, StateDraft).
+			Project("title", "author").
+			Sort("-pages", "title").
+			Page(3, 20)
+
+		values, err := q.Values()
+		require.NoError(t, err)
+		require.Equal(t, `{"author":"Calvino","pages":300}`, values.Get("_q"))
+		require.Equal(t, "PUBLIC,DRAFT", values.Get("_st"))
+		require.Equal(t, "title,author", values.Get("_p"))
+		require.Equal(t, "-pages,title", values.Get("_s"))
+		require.Equal(t, "20", values.Get("_l"))
+		require.Equal(t, "40", values.Get("_sk"))
+
+		// queries are not modified by derived ones
+		values, err = base.Values()
+		require.NoError(t, err)
+		require.Equal(t, `{"author":"Calvino"}`, values.Get("_q"))
+		require.Empty(t, values.Get("_l"))
+	})
+
+	t.Run("empty query has no parameters", func(t *testing.T) {
+		values, err := NewQuery().Page(0, 0).Values()
+		require.NoError(t, err)
+		require.Empty(t, values)
+	})
+
+	t.Run("report filters that can not be encoded", func(t *testing.T) {
+		_, err := NewQuery().Where("invalid", func() {}).Values()
+		require.Error(t, err)
+	})
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create and read documents", func(t *testing.T) {
+		c, _ := newTestClient(t)
+
+		id, err := c.Create(ctx, book{Title: "Il barone rampante", Author: "Calvino", Pages: 280})
+		require.NoError(t, err)
+		ids, err := c.CreateMany(ctx, []book{
+			{Title: "Se una notte d'inverno un viaggiatore", Author: "Calvino", Pages: 260},
+			{Title: "Il nome della rosa", Author: "Eco", Pages: 500},
+		})
+		require.NoError(t, err)
+		require.Len(t, ids, 2)
+
+		document, err := c.Get(ctx, id, NewQuery())
+		require.NoError(t, err)
+		require.Equal(t, book{ID: id, Title: "Il barone rampante", Author: "Calvino", Pages: 280, State: StatePublic}, document)
+
+		documents, err := c.List(ctx, NewQuery().Where("author", "Calvino").Project("title"))
+		require.NoError(t, err)
+		require.Equal(t, []book{
+			{ID: id, Title: "Il barone rampante"},
+			{ID: ids[0], Title: "Se una notte d'inverno un viaggiatore"},
+		}, documents)
+
+		count, err := c.Count(ctx, NewQuery())
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+
+		exported := make([]string, 0)
+		require.NoError(t, c.Export(ctx, NewQuery().Where("author", "Eco"), func(b book) error {
+			exported = append(exported, b.Title)
+			return nil
+		}))
+		require.Equal(t, []string{"Il nome della rosa"}, exported)
+	})
+
+	t.Run("paginate documents", func(t *testing.T) {
+		c, _ := newTestClient(t)
+
+		documents := make([]book, 0, 5)
+		for i := 0; i < 5; i++ {
+			documents = append(documents, book{Title: "volume", Pages: i + 1})
+		}
+		_, err := c.CreateMany(ctx, documents)
+		require.NoError(t, err)
+
+		sizes := make([]int, 0)
+		require.NoError(t, c.Paginate(ctx, NewQuery(), 2, func(page []book) error {
+			sizes = append(sizes, len(page))
+			return nil
+		}))
+		require.Equal(t, []int{2, 2, 1}, sizes)
+
+		stop := errors.New("stop")
+		require.ErrorIs(t, c.Paginate(ctx, NewQuery(), 2, func(page []book) error { return stop }), stop)
+		require.Error(t, c.Paginate(ctx, NewQuery(), 0, func(page []book) error { return nil }))
+	})
+
+	t.Run("update documents", func(t *testing.T) {
+		c, _ := newTestClient(t)
+
+		id, err := c.Create(ctx, book{Title: "Marcovaldo", Author: "Calvino", Pages: 150})
+		require.NoError(t, err)
+		_, err = c.Create(ctx, book{Title: "Baudolino", Author: "Eco", Pages: 520})
+		require.NoError(t, err)
+
+		updated, err := c.Patch(ctx, id, Update{Set: map[string]interface{}{"title": "Marcovaldo ovvero le stagioni in città"}, Inc: map[string]float64{"pages": 10}}, NewQuery())
+		require.NoError(t, err)
+		require.Equal(t, "Marcovaldo ovvero le stagioni in città", updated.Title)
+		require.Equal(t, 160, updated.Pages)
+
+		count, err := c.PatchMany(ctx, NewQuery().Where("author", "Eco"), Update{Unset: map[string]bool{"pages": true}})
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		count, err = c.PatchBulk(ctx, []PatchOperation{
+			{Filter: Filter{"_id": id}, Update: Update{Set: map[string]interface{}{"pages": 200}}},
+			{Filter: Filter{"author": "Eco"}, Update: Update{Set: map[string]interface{}{"pages": 500}}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		documents, err := c.List(ctx, NewQuery())
+		require.NoError(t, err)
+		require.Equal(t, 200, documents[0].Pages)
+		require.Equal(t, 500, documents[1].Pages)
+	})
+
+	t.Run("move documents between states", func(t *testing.T) {
+		c, _ := newTestClient(t)
+
+		ids, err := c.CreateMany(ctx, []book{{Title: "first"}, {Title: "second"}, {Title: "third"}})
+		require.NoError(t, err)
+
+		require.NoError(t, c.SetState(ctx, ids[0], StateDraft))
+		count, err := c.SetStates(ctx, []StateTransition{{Filter: Filter{"_id": ids[1]}, StateTo: StateTrash}})
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		count, err = c.Count(ctx, NewQuery())
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+
+		documents, err := c.List(ctx, NewQuery().States(StateDraft, StateTrash))
+		require.NoError(t, err)
+		require.Len(t, documents, 2)
+
+		_, err = c.Get(ctx, ids[0], NewQuery())
+		require.ErrorIs(t, err, ErrNotFound)
+		document, err := c.Get(ctx, ids[0], NewQuery().States(StateDraft))
+		require.NoError(t, err)
+		require.Equal(t, StateDraft, document.State)
+	})
+
+	t.Run("delete documents", func(t *testing.T) {
+		c, _ := newTestClient(t)
+
+		ids, err := c.CreateMany(ctx, []book{{Title: "first", Author: "Eco"}, {Title: "second", Author: "Eco"}, {Title: "third"}})
+		require.NoError(t, err)
+
+		require.NoError(t, c.Delete(ctx, ids[2]))
+		count, err := c.DeleteMany(ctx, NewQuery().Where("author", "Eco"))
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+
+		count, err = c.Count(ctx, NewQuery())
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+
+	t.Run("map error responses", func(t *testing.T) {
+		c, _ := newTestClient(t)
+
+		_, err := c.Get(ctx, "missing", NewQuery())
+		require.ErrorIs(t, err, ErrNotFound)
+		var crudErr *Error
+		require.ErrorAs(t, err, &crudErr)
+		require.Equal(t, &Error{StatusCode: http.StatusNotFound, Code: "Not Found", Message: "resource not found"}, crudErr)
+		require.Equal(t, "crud service responded with status 404: resource not found", err.Error())
+
+		require.ErrorIs(t, c.Delete(ctx, "missing"), ErrNotFound)
+
+		_, err = c.Create(ctx, book{ID: "duplicated", Title: "first"})
+		require.NoError(t, err)
+		_, err = c.Create(ctx, book{ID: "duplicated", Title: "second"})
+		require.ErrorIs(t, err, ErrConflict)
+		require.False(t, errors.Is(err, ErrNotFound))
+
+		require.ErrorIs(t, c.SetState(ctx, "duplicated", ""), ErrBadRequest)
+	})
+
+	t.Run("map unavailable service", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		_, err := FromContext[book](ctx, server.URL).Count(ctx, NewQuery())
+		require.ErrorIs(t, err, ErrUnavailable)
+		require.Equal(t, "crud service responded with status 503", err.Error())
+	})
+
+	t.Run("forward headers of the incoming request", func(t *testing.T) {
+		fake := newFakeCRUD()
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		incoming := httptest.NewRequest(http.MethodGet, "/", nil)
+		incoming.Header.Set("miauserid", "user-1")
+		incoming.Header.Set(client.RequestIDHeader, "req-1")
+
+		client.Middleware(client.Config{Headers: []string{"miauserid", client.RequestIDHeader}})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err := FromContext[book](r.Context(), server.URL).List(r.Context(), NewQuery())
+				require.NoError(t, err)
+			}),
+		).ServeHTTP(httptest.NewRecorder(), incoming)
+
+		require.Equal(t, "user-1", fake.headers.Get("miauserid"))
+		require.Equal(t, "req-1", fake.headers.Get(client.RequestIDHeader))
+	})
+}
@@ -0,0 +1,69 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Errors matching the CRUD Service error responses through errors.Is
+var (
+	ErrBadRequest  = errors.New("request rejected by crud service")
+	ErrNotFound    = errors.New("document not found")
+	ErrConflict    = errors.New("document conflicts with an existing one")
+	ErrUnavailable = errors.New("crud service is not available")
+)
+
+// Error is an error response returned by CRUD Service
+type Error struct {
+	// StatusCode is the status code of the response
+	StatusCode int `json:"statusCode"`
+	// Code is the short description of the error, such as Not Found
+	Code string `json:"error"`
+	// Message explains the reason of the error
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("crud service responded with status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("crud service responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is maps the response status code to the matching sentinel error
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusBadGateway ||
+			e.StatusCode == http.StatusServiceUnavailable ||
+			e.StatusCode == http.StatusGatewayTimeout
+	default:
+		return false
+	}
+}
+
+// responseError reads the error response, falling back to the status code when its body can not be decoded
+func responseError(res *http.Response) error {
+	crudErr := &Error{StatusCode: res.StatusCode}
+
+	body, err := io.ReadAll(res.Body)
+	if err == nil && len(body) > 0 {
+		var decoded Error
+		if json.Unmarshal(body, &decoded) == nil {
+			crudErr.Code = decoded.Code
+			crudErr.Message = decoded.Message
+		}
+	}
+
+	return crudErr
+}
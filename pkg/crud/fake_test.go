@@ -0,0 +1,377 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeCRUD implements in memory the subset of CRUD Service API employed by the client.
+// Filters support only equality of top level fields, while inserted documents are PUBLIC
+type fakeCRUD struct {
+	mu        sync.Mutex
+	documents []map[string]interface{}
+	nextID    int
+	// headers records the headers of the last received request
+	headers http.Header
+	router  *chi.Mux
+}
+
+func newFakeCRUD() *fakeCRUD {
+	f := &fakeCRUD{router: chi.NewRouter()}
+
+	f.router.Get("/", f.list)
+	f.router.Get("/count", f.count)
+	f.router.Get("/export", f.export)
+	f.router.Get("/{id}", f.get)
+	f.router.Post("/", f.create)
+	f.router.Post("/bulk", f.createMany)
+	f.router.Post("/state", f.setStates)
+	f.router.Post("/{id}/state", f.setState)
+	f.router.Patch("/", f.patchMany)
+	f.router.Patch("/bulk", f.patchBulk)
+	f.router.Patch("/{id}", f.patch)
+	f.router.Delete("/", f.deleteMany)
+	f.router.Delete("/{id}", f.delete)
+
+	return f
+}
+
+func (f *fakeCRUD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.headers = r.Header.Clone()
+	f.router.ServeHTTP(w, r)
+}
+
+func (f *fakeCRUD) list(w http.ResponseWriter, r *http.Request) {
+	documents, ok := f.selected(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	skip, _ := strconv.Atoi(query.Get("_sk"))
+	limit, _ := strconv.Atoi(query.Get("_l"))
+	if skip > len(documents) {
+		skip = len(documents)
+	}
+	documents = documents[skip:]
+	if limit > 0 && limit < len(documents) {
+		documents = documents[:limit]
+	}
+
+	result := make([]map[string]interface{}, 0, len(documents))
+	for _, document := range documents {
+		result = append(result, project(document, query.Get("_p")))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (f *fakeCRUD) count(w http.ResponseWriter, r *http.Request) {
+	if documents, ok := f.selected(w, r); ok {
+		writeJSON(w, http.StatusOK, len(documents))
+	}
+}
+
+func (f *fakeCRUD) export(w http.ResponseWriter, r *http.Request) {
+	documents, ok := f.selected(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	for _, document := range documents {
+		_ = encoder.Encode(project(document, r.URL.Query().Get("_p")))
+	}
+}
+
+func (f *fakeCRUD) get(w http.ResponseWriter, r *http.Request) {
+	documents, ok := f.selected(w, r)
+	if !ok {
+		return
+	}
+
+	for _, document := range documents {
+		if document["_id"] == chi.URLParam(r, "id") {
+			writeJSON(w, http.StatusOK, project(document, r.URL.Query().Get("_p")))
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "resource not found")
+}
+
+func (f *fakeCRUD) create(w http.ResponseWriter, r *http.Request) {
+	var document map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&document); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be an object")
+		return
+	}
+
+	id, ok := f.insert(w, document)
+	if ok {
+		writeJSON(w, http.StatusOK, map[string]string{"_id": id})
+	}
+}
+
+func (f *fakeCRUD) createMany(w http.ResponseWriter, r *http.Request) {
+	var documents []map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&documents); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be an array")
+		return
+	}
+
+	ids := make([]map[string]string, 0, len(documents))
+	for _, document := range documents {
+		id, ok := f.insert(w, document)
+		if !ok {
+			return
+		}
+		ids = append(ids, map[string]string{"_id": id})
+	}
+	writeJSON(w, http.StatusOK, ids)
+}
+
+func (f *fakeCRUD) insert(w http.ResponseWriter, document map[string]interface{}) (string, bool) {
+	if id, found := document["_id"]; found {
+		for _, existing := range f.documents {
+			if existing["_id"] == id {
+				writeError(w, http.StatusConflict, "E11000 duplicate key error")
+				return "", false
+			}
+		}
+	} else {
+		f.nextID++
+		document["_id"] = fmt.Sprintf("id-%d", f.nextID)
+	}
+	document["__STATE__"] = string(StatePublic)
+	f.documents = append(f.documents, document)
+
+	return document["_id"].(string), true
+}
+
+func (f *fakeCRUD) patch(w http.ResponseWriter, r *http.Request) {
+	var update Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be an update")
+		return
+	}
+
+	documents, ok := f.selected(w, r)
+	if !ok {
+		return
+	}
+	for _, document := range documents {
+		if document["_id"] == chi.URLParam(r, "id") {
+			apply(document, update)
+			writeJSON(w, http.StatusOK, document)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "resource not found")
+}
+
+func (f *fakeCRUD) patchMany(w http.ResponseWriter, r *http.Request) {
+	var update Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be an update")
+		return
+	}
+
+	documents, ok := f.selected(w, r)
+	if !ok {
+		return
+	}
+	for _, document := range documents {
+		apply(document, update)
+	}
+	writeJSON(w, http.StatusOK, len(documents))
+}
+
+func (f *fakeCRUD) patchBulk(w http.ResponseWriter, r *http.Request) {
+	var operations []PatchOperation
+	if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be an array")
+		return
+	}
+
+	count := 0
+	for _, operation := range operations {
+		for _, document := range f.documents {
+			if matches(document, operation.Filter) {
+				apply(document, operation.Update)
+				count++
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, count)
+}
+
+func (f *fakeCRUD) delete(w http.ResponseWriter, r *http.Request) {
+	for i, document := range f.documents {
+		if document["_id"] == chi.URLParam(r, "id") {
+			f.documents = append(f.documents[:i], f.documents[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "resource not found")
+}
+
+func (f *fakeCRUD) deleteMany(w http.ResponseWriter, r *http.Request) {
+	documents, ok := f.selected(w, r)
+	if !ok {
+		return
+	}
+
+	remaining := make([]map[string]interface{}, 0, len(f.documents))
+	for _, document := range f.documents {
+		if !contains(documents, document) {
+			remaining = append(remaining, document)
+		}
+	}
+	f.documents = remaining
+	writeJSON(w, http.StatusOK, len(documents))
+}
+
+func (f *fakeCRUD) setState(w http.ResponseWriter, r *http.Request) {
+	var body StateTransition
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.StateTo == "" {
+		writeError(w, http.StatusBadRequest, "body must contain stateTo")
+		return
+	}
+
+	for _, document := range f.documents {
+		if document["_id"] == chi.URLParam(r, "id") {
+			document["__STATE__"] = string(body.StateTo)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "resource not found")
+}
+
+func (f *fakeCRUD) setStates(w http.ResponseWriter, r *http.Request) {
+	var transitions []StateTransition
+	if err := json.NewDecoder(r.Body).Decode(&transitions); err != nil {
+		writeError(w, http.StatusBadRequest, "body must be an array")
+		return
+	}
+
+	count := 0
+	for _, transition := range transitions {
+		for _, document := range f.documents {
+			if matches(document, transition.Filter) {
+				document["__STATE__"] = string(transition.StateTo)
+				count++
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, count)
+}
+
+// selected returns the documents matching the _q filter and the _st states, which default to PUBLIC
+func (f *fakeCRUD) selected(w http.ResponseWriter, r *http.Request) ([]map[string]interface{}, bool) {
+	query := r.URL.Query()
+
+	filter := Filter{}
+	if raw := query.Get("_q"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+			writeError(w, http.StatusBadRequest, "_q must be a valid JSON")
+			return nil, false
+		}
+	}
+
+	states := []string{string(StatePublic)}
+	if raw := query.Get("_st"); raw != "" {
+		states = strings.Split(raw, ",")
+	}
+
+	documents := make([]map[string]interface{}, 0)
+	for _, document := range f.documents {
+		if matches(document, filter) && containsState(states, document["__STATE__"]) {
+			documents = append(documents, document)
+		}
+	}
+
+	return documents, true
+}
+
+func matches(document map[string]interface{}, filter Filter) bool {
+	for key, value := range filter {
+		if fmt.Sprint(document[key]) != fmt.Sprint(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsState(states []string, state interface{}) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(documents []map[string]interface{}, target map[string]interface{}) bool {
+	for _, document := range documents {
+		if document["_id"] == target["_id"] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func apply(document map[string]interface{}, update Update) {
+	for key, value := range update.Set {
+		document[key] = value
+	}
+	for key := range update.Unset {
+		delete(document, key)
+	}
+	for key, value := range update.Inc {
+		current, _ := document[key].(float64)
+		document[key] = current + value
+	}
+}
+
+func project(document map[string]interface{}, projection string) map[string]interface{} {
+	if projection == "" {
+		return document
+	}
+
+	projected := map[string]interface{}{"_id": document["_id"]}
+	for _, field := range strings.Split(projection, ",") {
+		if value, found := document[field]; found {
+			projected[field] = value
+		}
+	}
+
+	return projected
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"statusCode": statusCode,
+		"error":      http.StatusText(statusCode),
+		"message":    message,
+	})
+}
@@ -0,0 +1,156 @@
+package crud
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// State is the publication state of a CRUD Service document
+type State string
+
+// States that CRUD Service documents can assume
+const (
+	StatePublic  State = "PUBLIC"
+	StateDraft   State = "DRAFT"
+	StateTrash   State = "TRASH"
+	StateDeleted State = "DELETED"
+)
+
+// Filter is a MongoDB query selecting the documents, such as {"price": {"$gt": 10}}
+type Filter map[string]interface{}
+
+// Query describes which documents are selected and how they are returned.
+// Its methods return a modified copy, so that queries can be composed and reused
+type Query struct {
+	filter     Filter
+	states     []State
+	projection []string
+	sort       []string
+	limit      int
+	skip       int
+}
+
+// NewQuery creates a query selecting all the documents in the default states
+func NewQuery() Query {
+	return Query{}
+}
+
+// Filter returns a copy of the query selecting the documents matching the filter (_q)
+func (q Query) Filter(filter Filter) Query {
+	q.filter = filter
+	return q
+}
+
+// Where returns a copy of the query that additionally selects documents whose field is equal to value
+func (q Query) Where(field string, value interface{}) Query {
+	filter := make(Filter, len(q.filter)+1)
+	for key, v := range q.filter {
+		filter[key] = v
+	}
+	filter[field] = value
+	q.filter = filter
+
+	return q
+}
+
+// States returns a copy of the query selecting documents in the given states (_st)
+func (q Query) States(states ...State) Query {
+	q.states = states
+	return q
+}
+
+// Project returns a copy of the query returning only the given fields (_p)
+func (q Query) Project(fields ...string) Query {
+	q.projection = fields
+	return q
+}
+
+// Sort returns a copy of the query sorting documents by the given fields (_s),
+// where fields prefixed by - are sorted in descending order
+func (q Query) Sort(fields ...string) Query {
+	q.sort = fields
+	return q
+}
+
+// Limit returns a copy of the query returning at most n documents (_l)
+func (q Query) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+// Skip returns a copy of the query skipping the first n documents (_sk)
+func (q Query) Skip(n int) Query {
+	q.skip = n
+	return q
+}
+
+// Page returns a copy of the query returning the given page, starting from 1, of the provided size
+func (q Query) Page(page, size int) Query {
+	if page < 1 {
+		page = 1
+	}
+	q.limit = size
+	q.skip = (page - 1) * size
+
+	return q
+}
+
+// Values encodes the query into the querystring parameters understood by CRUD Service
+func (q Query) Values() (url.Values, error) {
+	values := url.Values{}
+
+	if len(q.filter) > 0 {
+		filter, err := json.Marshal(q.filter)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("_q", string(filter))
+	}
+	if len(q.states) > 0 {
+		states := make([]string, 0, len(q.states))
+		for _, state := range q.states {
+			states = append(states, string(state))
+		}
+		values.Set("_st", strings.Join(states, ","))
+	}
+	if len(q.projection) > 0 {
+		values.Set("_p", strings.Join(q.projection, ","))
+	}
+	if len(q.sort) > 0 {
+		values.Set("_s", strings.Join(q.sort, ","))
+	}
+	if q.limit > 0 {
+		values.Set("_l", strconv.Itoa(q.limit))
+	}
+	if q.skip > 0 {
+		values.Set("_sk", strconv.Itoa(q.skip))
+	}
+
+	return values, nil
+}
+
+// Update describes the changes applied to the documents by PATCH requests
+type Update struct {
+	// Set assigns the value to each field
+	Set map[string]interface{} `json:"$set,omitempty"`
+	// Unset removes the fields
+	Unset map[string]bool `json:"$unset,omitempty"`
+	// Inc increments each field by the value
+	Inc map[string]float64 `json:"$inc,omitempty"`
+	// Push appends the value to each array field
+	Push map[string]interface{} `json:"$push,omitempty"`
+}
+
+// PatchOperation applies an update to the documents matching the filter within a bulk request
+type PatchOperation struct {
+	Filter Filter `json:"filter"`
+	Update Update `json:"update"`
+}
+
+// StateTransition moves the documents matching the filter to a new state within a bulk request
+type StateTransition struct {
+	Filter  Filter `json:"filter"`
+	StateTo State  `json:"stateTo"`
+}
@@ -0,0 +1,206 @@
+package decorator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/danibix95/miabase/pkg/response"
+)
+
+// abortStatusCode is the status code that tells the gateway to interrupt the decorators chain
+const abortStatusCode = http.StatusTeapot
+
+// Values holds the headers or query params of the original request or response.
+// The gateway forwards repeated values as arrays and single ones as strings, which are both accepted
+type Values map[string][]string
+
+// Get returns the first value associated with the key, or an empty string when the key is missing
+func (v Values) Get(key string) string {
+	if values := v[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// UnmarshalJSON decodes an object whose members are either strings or arrays of strings
+func (v *Values) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	values := make(Values, len(members))
+	for key, member := range members {
+		var single string
+		if err := json.Unmarshal(member, &single); err == nil {
+			values[key] = []string{single}
+			continue
+		}
+
+		var multiple []string
+		if err := json.Unmarshal(member, &multiple); err != nil {
+			return fmt.Errorf("value of %s must be a string or an array of strings", key)
+		}
+		values[key] = multiple
+	}
+	*v = values
+
+	return nil
+}
+
+// Request is the original request that the gateway forwards to decorators
+type Request struct {
+	Method  string          `json:"method"`
+	Path    string          `json:"path"`
+	Headers Values          `json:"headers"`
+	Query   Values          `json:"query"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// DecodeBody decodes the JSON body of the original request into target
+func (r Request) DecodeBody(target interface{}) error {
+	return decodeBody(r.Body, target)
+}
+
+// Response is the original response that the gateway forwards to POST decorators
+type Response struct {
+	StatusCode int             `json:"statusCode"`
+	Headers    Values          `json:"headers"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// DecodeBody decodes the JSON body of the original response into target
+func (r Response) DecodeBody(target interface{}) error {
+	return decodeBody(r.Body, target)
+}
+
+// PostRequest is the envelope that the gateway sends to POST decorators
+type PostRequest struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// PreHandler handles the calls of the gateway to a PRE decorator, which receive the original request,
+// replying whether the original request must be changed or the chain aborted
+type PreHandler func(r *http.Request, pre Request) Reply
+
+// PostHandler handles the calls of the gateway to a POST decorator,
+// replying whether the original response must be changed or the chain aborted
+type PostHandler func(r *http.Request, post PostRequest) Reply
+
+// RequestChanges lists the parts of the original request replaced by a PRE decorator.
+// Empty fields leave the corresponding part unchanged
+type RequestChanges struct {
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Query   map[string]string `json:"query,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+// ResponseChanges lists the parts of the original response replaced by a POST decorator.
+// Empty fields leave the corresponding part unchanged
+type ResponseChanges struct {
+	StatusCode int               `json:"statusCode,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       interface{}       `json:"body,omitempty"`
+}
+
+type abortedChain struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers"`
+	Body       interface{}       `json:"body"`
+}
+
+// Reply is the outcome of a decorator, serialized in the format expected by the gateway
+type Reply struct {
+	statusCode int
+	body       interface{}
+}
+
+// LeaveOriginalUnchanged replies that the gateway must continue with the original request or response
+func LeaveOriginalUnchanged() Reply {
+	return Reply{statusCode: http.StatusNoContent}
+}
+
+// ChangeOriginalRequest replies that the gateway must continue with the request modified by the changes.
+// It is meant to be returned by PRE decorators
+func ChangeOriginalRequest(changes RequestChanges) Reply {
+	return Reply{statusCode: http.StatusOK, body: changes}
+}
+
+// ChangeOriginalResponse replies that the gateway must return the response modified by the changes.
+// It is meant to be returned by POST decorators
+func ChangeOriginalResponse(changes ResponseChanges) Reply {
+	return Reply{statusCode: http.StatusOK, body: changes}
+}
+
+// AbortChain replies that the gateway must interrupt the decorators chain,
+// immediately returning to the caller a response with the given status code, headers and body
+func AbortChain(statusCode int, body interface{}, headers map[string]string) Reply {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+
+	return Reply{
+		statusCode: abortStatusCode,
+		body:       abortedChain{StatusCode: statusCode, Headers: headers, Body: body},
+	}
+}
+
+// Write sends the reply to the gateway. The body is encoded before writing the status code,
+// so that encoding failures are reported as 500 problems rather than as changes to apply
+func (r Reply) Write(rw http.ResponseWriter, req *http.Request) {
+	if r.body == nil {
+		rw.WriteHeader(r.statusCode)
+		return
+	}
+
+	body, err := json.Marshal(r.body)
+	if err != nil {
+		response.WriteProblem(rw, req, response.NewProblem(http.StatusInternalServerError, "decorator reply can not be encoded"))
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(r.statusCode)
+	_, _ = rw.Write(append(body, '\n'))
+}
+
+// PreHandlerFunc converts the PRE decorator handler into an http handler
+// that decodes the gateway envelope and writes the handler reply
+func PreHandlerFunc(handler PreHandler) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		var pre Request
+		if err := json.NewDecoder(r.Body).Decode(&pre); err != nil {
+			response.BadRequest(rw, r, "decorator request is not valid")
+			return
+		}
+
+		handler(r, pre).Write(rw, r)
+	}
+}
+
+// PostHandlerFunc converts the POST decorator handler into an http handler
+// that decodes the gateway envelope and writes the handler reply
+func PostHandlerFunc(handler PostHandler) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		var post PostRequest
+		if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+			response.BadRequest(rw, r, "decorator request is not valid")
+			return
+		}
+
+		handler(r, post).Write(rw, r)
+	}
+}
+
+func decodeBody(body json.RawMessage, target interface{}) error {
+	if len(body) == 0 {
+		return errors.New("body is empty")
+	}
+
+	return json.Unmarshal(body, target)
+}
@@ -0,0 +1,154 @@
+package decorator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreHandlerFunc(t *testing.T) {
+	envelope := `{
+		"method": "POST",
+		"path": "/orders",
+		"headers": {"miauserid": "user-1"},
+		"query": {"dryRun": "true"},
+		"body": {"total": 42}
+	}`
+
+	call := func(handler PreHandler, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		PreHandlerFunc(handler).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/pre", strings.NewReader(body)))
+		return rr
+	}
+
+	t.Run("decode the original request", func(t *testing.T) {
+		var received Request
+		var order struct {
+			Total float64 `json:"total"`
+		}
+		rr := call(func(r *http.Request, pre Request) Reply {
+			received = pre
+			require.NoError(t, pre.DecodeBody(&order))
+			return LeaveOriginalUnchanged()
+		}, envelope)
+
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		require.Empty(t, rr.Body.String())
+		require.Equal(t, "POST", received.Method)
+		require.Equal(t, "/orders", received.Path)
+		require.Equal(t, Values{"miauserid": {"user-1"}}, received.Headers)
+		require.Equal(t, Values{"dryRun": {"true"}}, received.Query)
+		require.Equal(t, "user-1", received.Headers.Get("miauserid"))
+		require.Empty(t, received.Headers.Get("missing"))
+		require.Equal(t, float64(42), order.Total)
+	})
+
+	t.Run("decode repeated query params and headers", func(t *testing.T) {
+		var received Request
+		rr := call(func(r *http.Request, pre Request) Reply {
+			received = pre
+			return LeaveOriginalUnchanged()
+		}, `{"method":"GET","path":"/orders","headers":{"accept":"application/json","x-forwarded-for":["10.0.0.1","10.0.0.2"]},"query":{"a":["1","2"],"b":"3"}}`)
+
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		require.Equal(t, Values{"a": {"1", "2"}, "b": {"3"}}, received.Query)
+		require.Equal(t, "1", received.Query.Get("a"))
+		require.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, received.Headers["x-forwarded-for"])
+	})
+
+	t.Run("change the original request", func(t *testing.T) {
+		rr := call(func(r *http.Request, pre Request) Reply {
+			return ChangeOriginalRequest(RequestChanges{
+				Headers: map[string]string{"x-discount": "10"},
+				Body:    map[string]int{"total": 38},
+			})
+		}, envelope)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"headers":{"x-discount":"10"},"body":{"total":38}}`, rr.Body.String())
+	})
+
+	t.Run("reply internal server error when changes can not be encoded", func(t *testing.T) {
+		rr := call(func(r *http.Request, pre Request) Reply {
+			return ChangeOriginalRequest(RequestChanges{Body: map[string]interface{}{"callback": func() {}}})
+		}, envelope)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, response.ProblemContentType, rr.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"decorator reply can not be encoded","instance":"/pre"}`, rr.Body.String())
+	})
+
+	t.Run("abort the chain", func(t *testing.T) {
+		rr := call(func(r *http.Request, pre Request) Reply {
+			return AbortChain(http.StatusForbidden, map[string]string{"message": "not allowed"}, nil)
+		}, envelope)
+
+		require.Equal(t, http.StatusTeapot, rr.Code)
+		require.JSONEq(t, `{"statusCode":403,"headers":{},"body":{"message":"not allowed"}}`, rr.Body.String())
+	})
+
+	t.Run("reject invalid envelopes", func(t *testing.T) {
+		rr := call(func(r *http.Request, pre Request) Reply {
+			t.Fatal("handler should not be called")
+			return LeaveOriginalUnchanged()
+		}, `{"headers": "invalid"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = call(func(r *http.Request, pre Request) Reply {
+			t.Fatal("handler should not be called")
+			return LeaveOriginalUnchanged()
+		}, `{"query": {"a": [1, 2]}}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("report missing body", func(t *testing.T) {
+		call(func(r *http.Request, pre Request) Reply {
+			require.Error(t, pre.DecodeBody(&struct{}{}))
+			return LeaveOriginalUnchanged()
+		}, `{"method": "GET", "path": "/orders"}`)
+	})
+}
+
+func TestPostHandlerFunc(t *testing.T) {
+	envelope := `{
+		"request": {"method": "GET", "path": "/orders/1", "headers": {}, "query": {}},
+		"response": {"statusCode": 200, "headers": {"content-type": "application/json", "set-cookie": ["a=1", "b=2"]}, "body": {"id": "1", "secret": "s"}}
+	}`
+
+	call := func(handler PostHandler) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		PostHandlerFunc(handler).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/post", strings.NewReader(envelope)))
+		return rr
+	}
+
+	t.Run("change the original response", func(t *testing.T) {
+		rr := call(func(r *http.Request, post PostRequest) Reply {
+			require.Equal(t, "/orders/1", post.Request.Path)
+			require.Equal(t, http.StatusOK, post.Response.StatusCode)
+			require.Equal(t, "application/json", post.Response.Headers.Get("content-type"))
+			require.Equal(t, []string{"a=1", "b=2"}, post.Response.Headers["set-cookie"])
+
+			var body map[string]string
+			require.NoError(t, post.Response.DecodeBody(&body))
+			delete(body, "secret")
+
+			return ChangeOriginalResponse(ResponseChanges{Body: body})
+		})
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"body":{"id":"1"}}`, rr.Body.String())
+	})
+
+	t.Run("leave the original response unchanged", func(t *testing.T) {
+		rr := call(func(r *http.Request, post PostRequest) Reply {
+			return LeaveOriginalUnchanged()
+		})
+
+		require.Equal(t, http.StatusNoContent, rr.Code)
+	})
+}
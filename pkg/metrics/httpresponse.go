@@ -1,6 +1,9 @@
 package metrics
 
 import (
+	"bufio"
+	"io"
+	"net"
 	"net/http"
 	"strconv"
 )
@@ -9,6 +12,10 @@ import (
 type httpResponseWriter struct {
 	writer http.ResponseWriter
 	status string
+	// size is the number of body bytes written
+	size int
+	// wroteHeader reports whether the response headers were already sent
+	wroteHeader bool
 }
 
 // Header return the Header map of the wrapped http ResponseWriter
@@ -16,15 +23,24 @@ func (hrw *httpResponseWriter) Header() http.Header {
 	return hrw.writer.Header()
 }
 
-// Write execute the Write method on the wrapped http ResponseWriter
+// Write execute the Write method on the wrapped http ResponseWriter,
+// counting the written bytes
 func (hrw *httpResponseWriter) Write(body []byte) (int, error) {
-	return hrw.writer.Write(body)
+	hrw.wroteHeader = true
+	n, err := hrw.writer.Write(body)
+	hrw.size += n
+	return n, err
 }
 
 // WriteHeader store the statusCode within the response wrapper and then
-// execute the http ResponseWriter's WriteHeade method
+// execute the http ResponseWriter's WriteHeade method. Only the first final status code is stored,
+// since informational responses can precede it and later calls are ignored
 func (hrw *httpResponseWriter) WriteHeader(statusCode int) {
-	hrw.status = strconv.Itoa(statusCode)
+	informational := statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols
+	if !hrw.wroteHeader && !informational {
+		hrw.status = strconv.Itoa(statusCode)
+		hrw.wroteHeader = true
+	}
 	hrw.writer.WriteHeader(statusCode)
 }
 
@@ -32,6 +48,159 @@ func (hrw *httpResponseWriter) WriteHeader(statusCode int) {
 // it execute the Flush method
 func (hrw *httpResponseWriter) Flush() {
 	if f, ok := hrw.writer.(http.Flusher); ok {
+		hrw.wroteHeader = true
 		f.Flush()
 	}
 }
+
+// Hijack execute the Hijack method of the wrapped http ResponseWriter,
+// returning an error when it does not implement the Hijacker interface
+func (hrw *httpResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := hrw.writer.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// ReadFrom execute the ReadFrom method of the wrapped http ResponseWriter, which can
+// send files without copying them, counting the written bytes
+func (hrw *httpResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	hrw.wroteHeader = true
+	if rf, ok := hrw.writer.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(src)
+		hrw.size += int(n)
+		return n, err
+	}
+
+	// hide the ReadFrom method, so that io.Copy does not call it again
+	return io.Copy(struct{ io.Writer }{hrw}, src)
+}
+
+// Push execute the Push method of the wrapped http ResponseWriter,
+// returning an error when it does not implement the Pusher interface
+func (hrw *httpResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := hrw.writer.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap return the wrapped http ResponseWriter, so that http.ResponseController
+// can reach the features that are not exposed by the wrapper
+func (hrw *httpResponseWriter) Unwrap() http.ResponseWriter {
+	return hrw.writer
+}
+
+// unwrapper is implemented by response writers that wrap another one
+type unwrapper interface {
+	http.ResponseWriter
+	Unwrap() http.ResponseWriter
+}
+
+// decorated returns the response wrapper exposing only the optional interfaces
+// implemented by the wrapped http ResponseWriter, so that type assertions on it behave as on the original
+func (hrw *httpResponseWriter) decorated() http.ResponseWriter {
+	_, flusher := hrw.writer.(http.Flusher)
+	_, hijacker := hrw.writer.(http.Hijacker)
+	_, readerFrom := hrw.writer.(io.ReaderFrom)
+	_, pusher := hrw.writer.(http.Pusher)
+
+	switch {
+	case flusher && hijacker && readerFrom && pusher:
+		return struct {
+			unwrapper
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{hrw, hrw, hrw, hrw, hrw}
+	case flusher && hijacker && readerFrom:
+		return struct {
+			unwrapper
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{hrw, hrw, hrw, hrw}
+	case flusher && hijacker && pusher:
+		return struct {
+			unwrapper
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{hrw, hrw, hrw, hrw}
+	case flusher && readerFrom && pusher:
+		return struct {
+			unwrapper
+			http.Flusher
+			io.ReaderFrom
+			http.Pusher
+		}{hrw, hrw, hrw, hrw}
+	case hijacker && readerFrom && pusher:
+		return struct {
+			unwrapper
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{hrw, hrw, hrw, hrw}
+	case flusher && hijacker:
+		return struct {
+			unwrapper
+			http.Flusher
+			http.Hijacker
+		}{hrw, hrw, hrw}
+	case flusher && readerFrom:
+		return struct {
+			unwrapper
+			http.Flusher
+			io.ReaderFrom
+		}{hrw, hrw, hrw}
+	case flusher && pusher:
+		return struct {
+			unwrapper
+			http.Flusher
+			http.Pusher
+		}{hrw, hrw, hrw}
+	case hijacker && readerFrom:
+		return struct {
+			unwrapper
+			http.Hijacker
+			io.ReaderFrom
+		}{hrw, hrw, hrw}
+	case hijacker && pusher:
+		return struct {
+			unwrapper
+			http.Hijacker
+			http.Pusher
+		}{hrw, hrw, hrw}
+	case readerFrom && pusher:
+		return struct {
+			unwrapper
+			io.ReaderFrom
+			http.Pusher
+		}{hrw, hrw, hrw}
+	case flusher:
+		return struct {
+			unwrapper
+			http.Flusher
+		}{hrw, hrw}
+	case hijacker:
+		return struct {
+			unwrapper
+			http.Hijacker
+		}{hrw, hrw}
+	case readerFrom:
+		return struct {
+			unwrapper
+			io.ReaderFrom
+		}{hrw, hrw}
+	case pusher:
+		return struct {
+			unwrapper
+			http.Pusher
+		}{hrw, hrw}
+	default:
+		return struct {
+			unwrapper
+		}{hrw}
+	}
+}
@@ -0,0 +1,330 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/stretchr/testify/require"
+)
+
+// optional interfaces of the response writers, combined as bit flags
+const (
+	flusher = 1 << iota
+	hijacker
+	readerFrom
+	pusher
+	allInterfaces = flusher | hijacker | readerFrom | pusher
+)
+
+// fakeWriter records the calls to the optional interfaces of response writers
+type fakeWriter struct {
+	*httptest.ResponseRecorder
+	flushed       bool
+	hijacked      bool
+	readFrom      bool
+	pushed        string
+	writeDeadline time.Time
+}
+
+func (f *fakeWriter) Flush() {
+	f.flushed = true
+	f.ResponseRecorder.Flush()
+}
+
+func (f *fakeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	f.hijacked = true
+	return nil, nil, nil
+}
+
+func (f *fakeWriter) ReadFrom(src io.Reader) (int64, error) {
+	f.readFrom = true
+	return io.Copy(f.ResponseRecorder, src)
+}
+
+func (f *fakeWriter) Push(target string, opts *http.PushOptions) error {
+	f.pushed = target
+	return nil
+}
+
+func (f *fakeWriter) SetWriteDeadline(deadline time.Time) error {
+	f.writeDeadline = deadline
+	return nil
+}
+
+// newFakeWriter returns a response writer implementing only the selected optional interfaces
+func newFakeWriter(f *fakeWriter, interfaces int) http.ResponseWriter {
+	// write deadlines are always supported, to verify they are reached through Unwrap
+	type rw = interface {
+		http.ResponseWriter
+		SetWriteDeadline(deadline time.Time) error
+	}
+	switch interfaces {
+	case flusher | hijacker | readerFrom | pusher:
+		return struct {
+			rw
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{f, f, f, f, f}
+	case flusher | hijacker | readerFrom:
+		return struct {
+			rw
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{f, f, f, f}
+	case flusher | hijacker | pusher:
+		return struct {
+			rw
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{f, f, f, f}
+	case flusher | readerFrom | pusher:
+		return struct {
+			rw
+			http.Flusher
+			io.ReaderFrom
+			http.Pusher
+		}{f, f, f, f}
+	case hijacker | readerFrom | pusher:
+		return struct {
+			rw
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{f, f, f, f}
+	case flusher | hijacker:
+		return struct {
+			rw
+			http.Flusher
+			http.Hijacker
+		}{f, f, f}
+	case flusher | readerFrom:
+		return struct {
+			rw
+			http.Flusher
+			io.ReaderFrom
+		}{f, f, f}
+	case flusher | pusher:
+		return struct {
+			rw
+			http.Flusher
+			http.Pusher
+		}{f, f, f}
+	case hijacker | readerFrom:
+		return struct {
+			rw
+			http.Hijacker
+			io.ReaderFrom
+		}{f, f, f}
+	case hijacker | pusher:
+		return struct {
+			rw
+			http.Hijacker
+			http.Pusher
+		}{f, f, f}
+	case readerFrom | pusher:
+		return struct {
+			rw
+			io.ReaderFrom
+			http.Pusher
+		}{f, f, f}
+	case flusher:
+		return struct {
+			rw
+			http.Flusher
+		}{f, f}
+	case hijacker:
+		return struct {
+			rw
+			http.Hijacker
+		}{f, f}
+	case readerFrom:
+		return struct {
+			rw
+			io.ReaderFrom
+		}{f, f}
+	case pusher:
+		return struct {
+			rw
+			http.Pusher
+		}{f, f}
+	default:
+		return struct{ rw }{f}
+	}
+}
+
+func interfacesName(interfaces int) string {
+	names := make([]string, 0, 4)
+	for i, name := range []string{"Flusher", "Hijacker", "ReaderFrom", "Pusher"} {
+		if interfaces&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, "+")
+}
+
+func TestHTTPResponseWriterInterfaces(t *testing.T) {
+	for interfaces := 0; interfaces <= allInterfaces; interfaces++ {
+		interfaces := interfaces
+		t.Run(interfacesName(interfaces), func(t *testing.T) {
+			fake := &fakeWriter{ResponseRecorder: httptest.NewRecorder()}
+			underlying := newFakeWriter(fake, interfaces)
+			hrw := &httpResponseWriter{writer: underlying, status: "200"}
+			w := hrw.decorated()
+
+			_, isFlusher := w.(http.Flusher)
+			_, isHijacker := w.(http.Hijacker)
+			_, isReaderFrom := w.(io.ReaderFrom)
+			_, isPusher := w.(http.Pusher)
+			require.Equal(t, interfaces&flusher != 0, isFlusher, "Flusher preserved")
+			require.Equal(t, interfaces&hijacker != 0, isHijacker, "Hijacker preserved")
+			require.Equal(t, interfaces&readerFrom != 0, isReaderFrom, "ReaderFrom preserved")
+			require.Equal(t, interfaces&pusher != 0, isPusher, "Pusher preserved")
+
+			unwrapped, ok := w.(interface{ Unwrap() http.ResponseWriter })
+			require.True(t, ok, "Unwrap is always available")
+			require.Equal(t, underlying, unwrapped.Unwrap())
+
+			if isPusher {
+				require.NoError(t, w.(http.Pusher).Push("/style.css", nil))
+				require.Equal(t, "/style.css", fake.pushed)
+				require.False(t, hrw.wroteHeader, "pushing does not send the headers")
+			}
+
+			if isHijacker {
+				_, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				require.True(t, fake.hijacked)
+				return
+			}
+
+			w.WriteHeader(http.StatusAccepted)
+			require.True(t, hrw.wroteHeader)
+			require.Equal(t, "202", hrw.status)
+
+			if isReaderFrom {
+				n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+				require.NoError(t, err)
+				require.Equal(t, int64(5), n)
+				require.True(t, fake.readFrom)
+			} else {
+				_, err := io.WriteString(w, "hello")
+				require.NoError(t, err)
+			}
+
+			_, err := w.Write([]byte(" world"))
+			require.NoError(t, err)
+			require.Equal(t, len("hello world"), hrw.size)
+			require.Equal(t, "hello world", fake.Body.String())
+
+			if isFlusher {
+				w.(http.Flusher).Flush()
+				require.True(t, fake.flushed)
+			}
+		})
+	}
+}
+
+func TestHTTPResponseWriter(t *testing.T) {
+	newWriter := func() (*fakeWriter, *httpResponseWriter) {
+		fake := &fakeWriter{ResponseRecorder: httptest.NewRecorder()}
+		return fake, &httpResponseWriter{writer: newFakeWriter(fake, 0), status: "200"}
+	}
+
+	t.Run("report unsupported interfaces when called directly", func(t *testing.T) {
+		fake, hrw := newWriter()
+
+		_, _, err := hrw.Hijack()
+		require.ErrorIs(t, err, http.ErrNotSupported)
+		require.ErrorIs(t, hrw.Push("/style.css", nil), http.ErrNotSupported)
+
+		hrw.Flush()
+		require.False(t, fake.flushed)
+		require.False(t, hrw.wroteHeader, "headers are not sent by unsupported flushes")
+
+		n, err := hrw.ReadFrom(strings.NewReader("hello"))
+		require.NoError(t, err)
+		require.Equal(t, int64(5), n)
+		require.Equal(t, 5, hrw.size)
+		require.False(t, fake.readFrom)
+		require.Equal(t, "hello", fake.Body.String())
+	})
+
+	t.Run("record headers sent by writing the body", func(t *testing.T) {
+		_, hrw := newWriter()
+		require.False(t, hrw.wroteHeader)
+
+		_, err := hrw.Write([]byte("hello"))
+		require.NoError(t, err)
+		require.True(t, hrw.wroteHeader)
+
+		hrw.WriteHeader(http.StatusInternalServerError)
+		require.Equal(t, "200", hrw.status, "status can not change once the headers are sent")
+	})
+
+	t.Run("record headers sent by flushing", func(t *testing.T) {
+		fake := &fakeWriter{ResponseRecorder: httptest.NewRecorder()}
+		hrw := &httpResponseWriter{writer: newFakeWriter(fake, flusher), status: "200"}
+
+		hrw.Flush()
+		require.True(t, hrw.wroteHeader)
+		require.True(t, fake.flushed)
+	})
+
+	t.Run("record the first final status code", func(t *testing.T) {
+		_, hrw := newWriter()
+
+		hrw.WriteHeader(http.StatusEarlyHints)
+		require.False(t, hrw.wroteHeader, "informational responses do not send the final headers")
+		require.Equal(t, "200", hrw.status)
+
+		hrw.WriteHeader(http.StatusNotFound)
+		hrw.WriteHeader(http.StatusOK)
+		require.True(t, hrw.wroteHeader)
+		require.Equal(t, "404", hrw.status)
+	})
+}
+
+func TestRequestStatusHijack(t *testing.T) {
+	m := NewRequestMetrics(promauto.With(prometheus.NewRegistry()))
+	server := httptest.NewServer(m.RequestStatus()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(buf, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = buf.Flush()
+	})))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	require.Equal(t, "websocket", res.Header.Get("Upgrade"))
+}
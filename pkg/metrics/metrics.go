@@ -1,8 +1,11 @@
 package metrics
 
 import (
+	"io"
 	"net/http"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
@@ -14,11 +17,14 @@ const (
 	statusLabel = "status"
 	methodLabel = "method"
 	routeLabel  = "route"
-)
+	pluginLabel = "plugin"
 
-var (
-	requestDurationHistogram *prometheus.HistogramVec
-	requestDurationSummary   *prometheus.SummaryVec
+	traceParentHeader = "traceparent"
+	requestIDHeader   = "x-request-id"
+
+	// UnmatchedRoute is the route label of the requests that do not match any route,
+	// which are collapsed into a single value to avoid unbounded label values
+	UnmatchedRoute = "unmatched"
 )
 
 // Metrics is an interface that can be employed when using the service to
@@ -41,31 +47,185 @@ func InitializeMetrics(enableDefaultCollectors bool) (*prometheus.Registry, prom
 	return reg, promauto.With(reg)
 }
 
-// setRequestMetrics register a set of metrics usefult to monitor the requests that are performed to the service
-func setRequestMetrics(pf promauto.Factory) {
-	requestDurationHistogram = pf.NewHistogramVec(
+// Default names of the request metrics
+const (
+	DefaultDurationHistogramName = "http_request_duration_seconds"
+	DefaultDurationSummaryName   = "http_request_summary_seconds"
+	DefaultRequestSizeName       = "http_request_size_bytes"
+	DefaultResponseSizeName      = "http_response_size_bytes"
+	DefaultInFlightName          = "http_requests_in_flight"
+)
+
+var (
+	// DefaultDurationBuckets are the buckets of the request duration histogram, expressed in seconds
+	DefaultDurationBuckets = []float64{0.05, 0.1, 0.5, 1, 3, 5, 10}
+	// DefaultSummaryObjectives are the quantiles of the request duration summary with their absolute error
+	DefaultSummaryObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.95: 0.005, 0.99: 0.001}
+	// DefaultSizeBuckets are the buckets of request and response size histograms, expressed in bytes
+	DefaultSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
+)
+
+// RequestMetricsOpts customizes the metrics collected for the incoming requests.
+// Empty fields fall back to their default value
+type RequestMetricsOpts struct {
+	// PluginLabel adds to request metrics the plugin label, which reports the path
+	// where the router serving the route is mounted, such as the plugin path
+	PluginLabel bool
+	// Namespace and Subsystem are prepended to the names of request metrics
+	Namespace string
+	Subsystem string
+	// ConstLabels are added to each request metric, such as the service name and version
+	ConstLabels prometheus.Labels
+	// DurationBuckets are the buckets of the request duration histogram
+	DurationBuckets []float64
+	// SummaryObjectives are the quantiles of the request duration summary
+	SummaryObjectives map[float64]float64
+	// DisableSummary prevents the request duration summary from being collected
+	DisableSummary bool
+	// SizeBuckets are the buckets of request and response size histograms
+	SizeBuckets []float64
+	// DurationHistogramName is the name of the request duration histogram
+	DurationHistogramName string
+	// DurationSummaryName is the name of the request duration summary
+	DurationSummaryName string
+	// RequestSizeName is the name of the request size histogram
+	RequestSizeName string
+	// ResponseSizeName is the name of the response size histogram
+	ResponseSizeName string
+	// InFlightName is the name of the gauge counting the requests being served
+	InFlightName string
+	// Exemplar returns the exemplar labels attached to the observations of request histograms,
+	// such as the identifier of the trace the request belongs to. Defaults to RequestExemplar
+	Exemplar func(r *http.Request) prometheus.Labels
+}
+
+func (opts RequestMetricsOpts) labels() []string {
+	if opts.PluginLabel {
+		return []string{statusLabel, methodLabel, routeLabel, pluginLabel}
+	}
+
+	return []string{statusLabel, methodLabel, routeLabel}
+}
+
+func (opts RequestMetricsOpts) withDefaults() RequestMetricsOpts {
+	if len(opts.DurationBuckets) == 0 {
+		opts.DurationBuckets = DefaultDurationBuckets
+	}
+	if len(opts.SummaryObjectives) == 0 {
+		opts.SummaryObjectives = DefaultSummaryObjectives
+	}
+	if len(opts.SizeBuckets) == 0 {
+		opts.SizeBuckets = DefaultSizeBuckets
+	}
+	if opts.DurationHistogramName == "" {
+		opts.DurationHistogramName = DefaultDurationHistogramName
+	}
+	if opts.DurationSummaryName == "" {
+		opts.DurationSummaryName = DefaultDurationSummaryName
+	}
+	if opts.RequestSizeName == "" {
+		opts.RequestSizeName = DefaultRequestSizeName
+	}
+	if opts.ResponseSizeName == "" {
+		opts.ResponseSizeName = DefaultResponseSizeName
+	}
+	if opts.InFlightName == "" {
+		opts.InFlightName = DefaultInFlightName
+	}
+	if opts.Exemplar == nil {
+		opts.Exemplar = RequestExemplar
+	}
+
+	return opts
+}
+
+// RequestMetrics holds the metrics that monitor the requests received by a service.
+// Each service owns its instance, so that multiple services can live in the same process
+type RequestMetrics struct {
+	opts              RequestMetricsOpts
+	durationHistogram *prometheus.HistogramVec
+	durationSummary   *prometheus.SummaryVec
+	requestSize       *prometheus.HistogramVec
+	responseSize      *prometheus.HistogramVec
+	inFlight          prometheus.Gauge
+}
+
+// NewRequestMetrics register a set of metrics useful to monitor the requests that are performed to the service
+func NewRequestMetrics(pf promauto.Factory, opts ...RequestMetricsOpts) *RequestMetrics {
+	m := new(RequestMetrics)
+	if len(opts) > 0 {
+		m.opts = opts[0]
+	}
+	m.opts = m.opts.withDefaults()
+
+	m.durationHistogram = pf.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "request duration in seconds",
-			Buckets: []float64{0.05, 0.1, 0.5, 1, 3, 5, 10},
+			Namespace:   m.opts.Namespace,
+			Subsystem:   m.opts.Subsystem,
+			Name:        m.opts.DurationHistogramName,
+			Help:        "request duration in seconds",
+			ConstLabels: m.opts.ConstLabels,
+			Buckets:     m.opts.DurationBuckets,
 		},
-		[]string{statusLabel, methodLabel, routeLabel},
+		m.opts.labels(),
 	)
-	requestDurationSummary = pf.NewSummaryVec(
-		prometheus.SummaryOpts{
-			Name:       "http_request_summary_seconds",
-			Help:       "request duration in seconds summary",
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.95: 0.005, 0.99: 0.001},
+	if !m.opts.DisableSummary {
+		m.durationSummary = pf.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Namespace:   m.opts.Namespace,
+				Subsystem:   m.opts.Subsystem,
+				Name:        m.opts.DurationSummaryName,
+				Help:        "request duration in seconds summary",
+				ConstLabels: m.opts.ConstLabels,
+				Objectives:  m.opts.SummaryObjectives,
+			},
+			m.opts.labels(),
+		)
+	}
+	m.requestSize = pf.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   m.opts.Namespace,
+			Subsystem:   m.opts.Subsystem,
+			Name:        m.opts.RequestSizeName,
+			Help:        "request body size in bytes",
+			ConstLabels: m.opts.ConstLabels,
+			Buckets:     m.opts.SizeBuckets,
 		},
-		[]string{statusLabel, methodLabel, routeLabel},
+		m.opts.labels(),
 	)
+	m.responseSize = pf.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   m.opts.Namespace,
+			Subsystem:   m.opts.Subsystem,
+			Name:        m.opts.ResponseSizeName,
+			Help:        "response body size in bytes",
+			ConstLabels: m.opts.ConstLabels,
+			Buckets:     m.opts.SizeBuckets,
+		},
+		m.opts.labels(),
+	)
+	m.inFlight = pf.NewGauge(prometheus.GaugeOpts{
+		Namespace:   m.opts.Namespace,
+		Subsystem:   m.opts.Subsystem,
+		Name:        m.opts.InFlightName,
+		Help:        "number of requests being served",
+		ConstLabels: m.opts.ConstLabels,
+	})
+
+	return m
 }
 
-// RequestStatus return a http middleware that collects all the incoming http requests
-// and categorize them accoding to their route and response status code
-func RequestStatus(pf promauto.Factory) func(http.Handler) http.Handler {
-	setRequestMetrics(pf)
+// RequestStatus register the request metrics and return the middleware collecting them.
+// Since metrics are registered on each call, services should create their RequestMetrics
+// once and employ its RequestStatus method instead
+func RequestStatus(pf promauto.Factory, opts ...RequestMetricsOpts) func(http.Handler) http.Handler {
+	return NewRequestMetrics(pf, opts...).RequestStatus()
+}
 
+// RequestStatus return a http middleware that collects all the incoming http requests
+// and categorize them accoding to their route and response status code.
+// Routes are reported with their full pattern, even when they belong to mounted routers
+func (m *RequestMetrics) RequestStatus() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// avoid counting requests that does not belong to APIs
@@ -74,24 +234,169 @@ func RequestStatus(pf promauto.Factory) func(http.Handler) http.Handler {
 				return
 			}
 
+			m.inFlight.Inc()
+			defer m.inFlight.Dec()
+
 			start := time.Now()
 			// default to status 200 to avoid empty values when WriteHeader
 			// is not called to change the default status value 200 - OK
-			httpResponse := httpResponseWriter{w, "200"}
+			httpResponse := &httpResponseWriter{writer: w, status: "200"}
+			var body *countingBody
+			if r.Body != nil && r.Body != http.NoBody {
+				body = &countingBody{ReadCloser: r.Body}
+				r.Body = body
+			}
 
-			next.ServeHTTP(&httpResponse, r)
+			next.ServeHTTP(httpResponse.decorated(), r)
 
 			end := time.Since(start).Seconds()
 			// use path params patterns rather than actual value to avoid
 			// generating too many different values for path label
-			path := chi.RouteContext(r.Context()).RoutePattern()
-
-			requestDurationHistogram.
-				WithLabelValues(httpResponse.status, r.Method, path).
-				Observe(end)
-			requestDurationSummary.
-				WithLabelValues(httpResponse.status, r.Method, path).
-				Observe(end)
+			route, mountPath := resolveRoute(r)
+			labels := []string{httpResponse.status, r.Method, route}
+			if m.opts.PluginLabel {
+				labels = append(labels, mountPath)
+			}
+
+			exemplar := m.opts.Exemplar(r)
+			observe(m.durationHistogram.WithLabelValues(labels...), end, exemplar)
+			if m.durationSummary != nil {
+				m.durationSummary.WithLabelValues(labels...).Observe(end)
+			}
+			observe(m.requestSize.WithLabelValues(labels...), float64(body.size(r.ContentLength)), exemplar)
+			observe(m.responseSize.WithLabelValues(labels...), float64(httpResponse.size), exemplar)
 		})
 	}
 }
+
+// RequestExemplar returns the exemplar labels of the request, which link the observation
+// to the trace_id of the W3C traceparent header or, when missing, to the request_id header
+func RequestExemplar(r *http.Request) prometheus.Labels {
+	if traceID := traceIDFromParent(r.Header.Get(traceParentHeader)); traceID != "" {
+		return prometheus.Labels{"trace_id": traceID}
+	}
+	if requestID := r.Header.Get(requestIDHeader); requestID != "" {
+		return prometheus.Labels{"request_id": requestID}
+	}
+
+	return nil
+}
+
+// traceIDFromParent extracts the trace id from a traceparent header value,
+// formatted as version-traceid-parentid-flags
+func traceIDFromParent(traceParent string) string {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	for _, c := range parts[1] {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return ""
+		}
+	}
+
+	return parts[1]
+}
+
+// observe records the value attaching the exemplar, unless it is empty or it exceeds
+// the exemplar size limit, since invalid exemplars would cause the observer to panic
+func observe(observer prometheus.Observer, value float64, exemplar prometheus.Labels) {
+	exemplarObserver, ok := observer.(prometheus.ExemplarObserver)
+	if !ok || !validExemplar(exemplar) {
+		observer.Observe(value)
+		return
+	}
+
+	exemplarObserver.ObserveWithExemplar(value, exemplar)
+}
+
+func validExemplar(exemplar prometheus.Labels) bool {
+	if len(exemplar) == 0 {
+		return false
+	}
+
+	runes := 0
+	for name, value := range exemplar {
+		if !utf8.ValidString(value) || !validLabelName(name) {
+			return false
+		}
+		runes += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+	}
+
+	return runes <= prometheus.ExemplarMaxRunes
+}
+
+// validLabelName reports whether the name matches [a-zA-Z_][a-zA-Z0-9_]*
+func validLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= '0' && c <= '9' && i > 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// countingBody counts the bytes of the request body read by the handler
+type countingBody struct {
+	io.ReadCloser
+	read int64
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, err := cb.ReadCloser.Read(p)
+	cb.read += int64(n)
+	return n, err
+}
+
+// size returns the request body size, which is the declared content length
+// when the handler did not read the whole body
+func (cb *countingBody) size(contentLength int64) int64 {
+	if cb == nil {
+		return 0
+	}
+	if contentLength > cb.read {
+		return contentLength
+	}
+
+	return cb.read
+}
+
+// resolveRoute returns the full pattern of the route that served the request across mounted routers,
+// together with the path where the router serving it is mounted. Requests that do not match
+// any route are reported as UnmatchedRoute
+func resolveRoute(r *http.Request) (string, string) {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil || len(rctx.RoutePatterns) == 0 {
+		return UnmatchedRoute, ""
+	}
+
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	// not found and method not allowed handlers do not reveal whether
+	// the request matched, so that the request is matched again
+	if !rctx.Routes.Match(chi.NewRouteContext(), r.Method, path) {
+		return UnmatchedRoute, ""
+	}
+
+	route := strings.Join(rctx.RoutePatterns, "")
+	for strings.Contains(route, "/*/") {
+		route = strings.ReplaceAll(route, "/*/", "/")
+	}
+
+	mountPath := ""
+	if len(rctx.RoutePatterns) > 1 {
+		mountPath = strings.TrimSuffix(strings.TrimSuffix(rctx.RoutePatterns[0], "*"), "/")
+		if mountPath == "" {
+			mountPath = "/"
+		}
+	}
+
+	return route, mountPath
+}
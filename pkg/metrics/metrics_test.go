@@ -1,14 +1,17 @@
 package metrics
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -44,46 +47,47 @@ func TestInitializeMetrics(t *testing.T) {
 	})
 }
 
-func TestSetRequestMetrics(t *testing.T) {
+func TestNewRequestMetrics(t *testing.T) {
 	t.Run("verify default metrics are registered", func(t *testing.T) {
-		// check that pointers are nil before execution
-		require.Nil(t, requestDurationHistogram)
-		require.Nil(t, requestDurationSummary)
-
 		reg := prometheus.NewPedanticRegistry()
 		promFactory := promauto.With(reg)
 
+		var m *RequestMetrics
 		require.NotPanics(t, func() {
-			setRequestMetrics(promFactory)
+			m = NewRequestMetrics(promFactory)
 		}, "metrics are registered correctly")
 
-		// an object has been assigned to the pointers
-		require.NotNil(t, requestDurationHistogram)
-		require.NotNil(t, requestDurationSummary)
-
 		require.NotPanics(t, func() {
-			requestDurationHistogram.WithLabelValues("200", "GET", "/greetings").Observe(0.07)
-			requestDurationSummary.WithLabelValues("200", "GET", "/greetings").Observe(0.07)
+			m.durationHistogram.WithLabelValues("200", "GET", "/greetings").Observe(0.07)
+			m.durationSummary.WithLabelValues("200", "GET", "/greetings").Observe(0.07)
 		}, "metrics can be employed to observe some values")
 
-		require.Equal(t, 1, testutil.CollectAndCount(requestDurationHistogram, "http_request_duration_seconds"))
-		require.Equal(t, 1, testutil.CollectAndCount(requestDurationSummary, "http_request_summary_seconds"))
+		require.Equal(t, 1, testutil.CollectAndCount(m.durationHistogram, "http_request_duration_seconds"))
+		require.Equal(t, 1, testutil.CollectAndCount(m.durationSummary, "http_request_summary_seconds"))
+	})
+
+	t.Run("instances registered on different registries are independent", func(t *testing.T) {
+		first := NewRequestMetrics(promauto.With(prometheus.NewPedanticRegistry()))
+		second := NewRequestMetrics(promauto.With(prometheus.NewPedanticRegistry()))
+
+		first.durationHistogram.WithLabelValues("200", "GET", "/greetings").Observe(0.07)
+
+		require.Equal(t, 1, testutil.CollectAndCount(first.durationHistogram, "http_request_duration_seconds"))
+		require.Equal(t, 0, testutil.CollectAndCount(second.durationHistogram, "http_request_duration_seconds"))
 	})
 }
 
 func TestRequestStatus(t *testing.T) {
 	t.Run("execute middleware to check that metrics were called once", func(t *testing.T) {
 		reg := prometheus.NewPedanticRegistry()
-		promFactory := promauto.With(reg)
-
-		middleware := RequestStatus(promFactory)
+		m := NewRequestMetrics(promauto.With(reg))
 
 		router := chi.NewMux()
 		requestHandler := func(w http.ResponseWriter, req *http.Request) {
 			w.Write([]byte("thunderstorm"))
 		}
 
-		router.Handle("/", middleware(http.HandlerFunc(requestHandler)))
+		router.Handle("/", m.RequestStatus()(http.HandlerFunc(requestHandler)))
 
 		recorder := httptest.NewRecorder()
 		request := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -91,7 +95,210 @@ func TestRequestStatus(t *testing.T) {
 		router.ServeHTTP(recorder, request)
 
 		require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
-		require.Equal(t, 1, testutil.CollectAndCount(requestDurationHistogram, "http_request_duration_seconds"))
-		require.Equal(t, 1, testutil.CollectAndCount(requestDurationSummary, "http_request_summary_seconds"))
+		require.Equal(t, 1, testutil.CollectAndCount(m.durationHistogram, "http_request_duration_seconds"))
+		require.Equal(t, 1, testutil.CollectAndCount(m.durationSummary, "http_request_summary_seconds"))
+	})
+
+	t.Run("middlewares of the same instance share metrics", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		m := NewRequestMetrics(promauto.With(reg))
+
+		handler := func(w http.ResponseWriter, req *http.Request) {}
+		for _, path := range []string{"/first", "/second"} {
+			router := chi.NewMux()
+			router.Handle(path, m.RequestStatus()(http.HandlerFunc(handler)))
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+
+		require.Equal(t, 2, testutil.CollectAndCount(m.durationHistogram, "http_request_duration_seconds"))
+	})
+}
+
+func TestRequestRouteLabel(t *testing.T) {
+	// routeLabels serves the requests through a router with a mounted subrouter,
+	// returning the labels of the recorded request metrics
+	routeLabels := func(t *testing.T, opts RequestMetricsOpts, method, path string) map[string]string {
+		t.Helper()
+
+		reg := prometheus.NewPedanticRegistry()
+		router := chi.NewMux()
+		router.Use(RequestStatus(promauto.With(reg), opts))
+
+		ok := func(w http.ResponseWriter, req *http.Request) {}
+		router.Get("/-/healthz", ok)
+		subrouter := chi.NewRouter()
+		subrouter.Get("/", ok)
+		subrouter.Get("/{id}", ok)
+		subrouter.Get("/{id}/items/*", ok)
+		router.Mount("/orders", subrouter)
+		rootSubrouter := chi.NewRouter()
+		rootSubrouter.Post("/greet", ok)
+		router.Mount("/", rootSubrouter)
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+
+		families, err := reg.Gather()
+		require.NoError(t, err)
+		require.NotEmpty(t, families)
+		require.Len(t, families[0].GetMetric(), 1)
+
+		labels := make(map[string]string)
+		for _, label := range families[0].GetMetric()[0].GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		return labels
+	}
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		plugin string
+	}{
+		{name: "route of the main router", method: http.MethodGet, path: "/-/healthz", route: "/-/healthz", plugin: ""},
+		{name: "root route of a mounted router", method: http.MethodGet, path: "/orders", route: "/orders/", plugin: "/orders"},
+		{name: "route with params of a mounted router", method: http.MethodGet, path: "/orders/42", route: "/orders/{id}", plugin: "/orders"},
+		{name: "wildcard route of a mounted router", method: http.MethodGet, path: "/orders/42/items/a/b", route: "/orders/{id}/items/*", plugin: "/orders"},
+		{name: "route of a router mounted on root", method: http.MethodPost, path: "/greet", route: "/greet", plugin: "/"},
+		{name: "unmatched path", method: http.MethodGet, path: "/orders/42/unknown", route: UnmatchedRoute, plugin: ""},
+		{name: "unmatched method", method: http.MethodDelete, path: "/orders/42", route: UnmatchedRoute, plugin: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			labels := routeLabels(t, RequestMetricsOpts{}, tc.method, tc.path)
+			require.Equal(t, tc.route, labels[routeLabel])
+			_, found := labels[pluginLabel]
+			require.False(t, found, "plugin label is not added by default")
+
+			labels = routeLabels(t, RequestMetricsOpts{PluginLabel: true}, tc.method, tc.path)
+			require.Equal(t, tc.route, labels[routeLabel])
+			require.Equal(t, tc.plugin, labels[pluginLabel])
+		})
+	}
+}
+
+func TestRequestMetricsOpts(t *testing.T) {
+	serve := func(opts RequestMetricsOpts, handler http.HandlerFunc, body string) (*prometheus.Registry, *RequestMetrics) {
+		reg := prometheus.NewPedanticRegistry()
+		m := NewRequestMetrics(promauto.With(reg), opts)
+		router := chi.NewMux()
+		router.Use(m.RequestStatus())
+		router.Post("/items", handler)
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body)))
+		return reg, m
+	}
+
+	t.Run("customize names, buckets and const labels", func(t *testing.T) {
+		reg, _ := serve(RequestMetricsOpts{
+			Namespace:             "shop",
+			Subsystem:             "orders",
+			ConstLabels:           prometheus.Labels{"service_name": "orders"},
+			DurationBuckets:       []float64{1, 2},
+			DurationHistogramName: "latency_seconds",
+			DisableSummary:        true,
+		}, func(w http.ResponseWriter, r *http.Request) {}, "")
+
+		families, err := reg.Gather()
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(families))
+		for _, family := range families {
+			names = append(names, family.GetName())
+			for _, metric := range family.GetMetric() {
+				labels := make(map[string]string)
+				for _, label := range metric.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+				require.Equal(t, "orders", labels["service_name"])
+			}
+			if family.GetName() == "shop_orders_latency_seconds" {
+				require.Len(t, family.GetMetric()[0].GetHistogram().GetBucket(), 2)
+			}
+		}
+		require.ElementsMatch(t, []string{
+			"shop_orders_latency_seconds",
+			"shop_orders_http_request_size_bytes",
+			"shop_orders_http_response_size_bytes",
+			"shop_orders_http_requests_in_flight",
+		}, names)
+	})
+
+	t.Run("record request and response sizes", func(t *testing.T) {
+		_, m := serve(RequestMetricsOpts{}, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.ReadAll(r.Body)
+			_, _ = w.Write([]byte("thunderstorm"))
+		}, `{"name":"umbrella"}`)
+
+		sum := func(histogram *prometheus.HistogramVec) float64 {
+			metric := &dto.Metric{}
+			require.NoError(t, histogram.WithLabelValues("200", http.MethodPost, "/items").(prometheus.Histogram).Write(metric))
+			return metric.GetHistogram().GetSampleSum()
+		}
+
+		require.Equal(t, float64(len(`{"name":"umbrella"}`)), sum(m.requestSize))
+		require.Equal(t, float64(len("thunderstorm")), sum(m.responseSize))
+	})
+
+	t.Run("count in flight requests", func(t *testing.T) {
+		var inFlight float64
+		m := NewRequestMetrics(promauto.With(prometheus.NewPedanticRegistry()))
+		handler := m.RequestStatus()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight = testutil.ToFloat64(m.inFlight)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, float64(1), inFlight)
+		require.Equal(t, float64(0), testutil.ToFloat64(m.inFlight))
+	})
+}
+
+func TestRequestExemplar(t *testing.T) {
+	exemplar := func(t *testing.T, opts RequestMetricsOpts, headers map[string]string) map[string]string {
+		t.Helper()
+
+		m := NewRequestMetrics(promauto.With(prometheus.NewPedanticRegistry()), opts)
+		handler := m.RequestStatus()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		metric := &dto.Metric{}
+		require.NoError(t, m.durationHistogram.WithLabelValues("200", http.MethodGet, UnmatchedRoute).(prometheus.Histogram).Write(metric))
+		labels := make(map[string]string)
+		for _, bucket := range metric.GetHistogram().GetBucket() {
+			for _, label := range bucket.GetExemplar().GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+		}
+		return labels
+	}
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	t.Run("link observations to the trace id", func(t *testing.T) {
+		labels := exemplar(t, RequestMetricsOpts{}, map[string]string{"traceparent": traceParent, "x-request-id": "req-1"})
+		require.Equal(t, map[string]string{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"}, labels)
+	})
+
+	t.Run("fall back to the request id", func(t *testing.T) {
+		labels := exemplar(t, RequestMetricsOpts{}, map[string]string{"traceparent": "00-invalid-00f067aa0ba902b7-01", "x-request-id": "req-1"})
+		require.Equal(t, map[string]string{"request_id": "req-1"}, labels)
+	})
+
+	t.Run("skip missing or oversized exemplars", func(t *testing.T) {
+		require.Empty(t, exemplar(t, RequestMetricsOpts{}, nil))
+		require.Empty(t, exemplar(t, RequestMetricsOpts{}, map[string]string{"x-request-id": strings.Repeat("a", 100)}))
+	})
+
+	t.Run("customize exemplar labels", func(t *testing.T) {
+		labels := exemplar(t, RequestMetricsOpts{Exemplar: func(r *http.Request) prometheus.Labels {
+			return prometheus.Labels{"user": "user-1"}
+		}}, nil)
+		require.Equal(t, map[string]string{"user": "user-1"}, labels)
 	})
 }
@@ -0,0 +1,71 @@
+//go:build go1.20
+
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPResponseWriterController(t *testing.T) {
+	for interfaces := 0; interfaces <= allInterfaces; interfaces++ {
+		interfaces := interfaces
+		t.Run(interfacesName(interfaces), func(t *testing.T) {
+			fake := &fakeWriter{ResponseRecorder: httptest.NewRecorder()}
+			hrw := &httpResponseWriter{writer: newFakeWriter(fake, interfaces), status: "200"}
+			rc := http.NewResponseController(hrw.decorated())
+
+			deadline := time.Now().Add(time.Minute)
+			require.NoError(t, rc.SetWriteDeadline(deadline), "reached through Unwrap")
+			require.Equal(t, deadline, fake.writeDeadline)
+
+			if interfaces&hijacker != 0 {
+				_, _, err := rc.Hijack()
+				require.NoError(t, err)
+				require.True(t, fake.hijacked)
+			} else {
+				_, _, err := rc.Hijack()
+				require.ErrorIs(t, err, http.ErrNotSupported)
+			}
+
+			if interfaces&flusher != 0 {
+				require.NoError(t, rc.Flush())
+				require.True(t, fake.flushed)
+				require.True(t, hrw.wroteHeader)
+			} else {
+				require.ErrorIs(t, rc.Flush(), http.ErrNotSupported)
+				require.False(t, hrw.wroteHeader)
+			}
+		})
+	}
+
+	t.Run("reach the features of writers wrapped multiple times", func(t *testing.T) {
+		fake := &fakeWriter{ResponseRecorder: httptest.NewRecorder()}
+		inner := &httpResponseWriter{writer: newFakeWriter(fake, flusher|readerFrom), status: "200"}
+		outer := &httpResponseWriter{writer: inner.decorated(), status: "200"}
+		w := outer.decorated()
+
+		require.NoError(t, http.NewResponseController(w).Flush())
+		require.True(t, fake.flushed)
+
+		_, err := w.(io.ReaderFrom).ReadFrom(io.LimitReader(zeroReader{}, 10))
+		require.NoError(t, err)
+		require.Equal(t, 10, inner.size)
+		require.Equal(t, 10, outer.size)
+	})
+}
+
+// zeroReader produces an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
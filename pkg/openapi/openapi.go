@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Version is the OpenAPI specification version the generated documents adhere to
+	Version = "3.0.3"
+
+	// InPath identifies parameters that are part of the route path
+	InPath = "path"
+	// InQuery identifies parameters that are part of the request querystring
+	InQuery = "query"
+
+	jsonContentType = "application/json"
+)
+
+// chiParamRegex matches chi path parameters, optionally followed by their regular expression
+var chiParamRegex = regexp.MustCompile(`{([^:}]+)(:[^}]*)?}`)
+
+// Document represents the subset of an OpenAPI 3 document describing the service routes
+type Document struct {
+	OpenAPI string              `json:"openapi"`
+	Info    Info                `json:"info"`
+	Paths   map[string]PathItem `json:"paths"`
+}
+
+// Info provides the metadata about the service
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem maps each lowercase HTTP method to the operation available on a path
+type PathItem map[string]*Operation
+
+// Operation describes a single route. ACL is exported as the x-acl extension,
+// reporting the authorization expression the user must satisfy to call the route
+type Operation struct {
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	ACL         string              `json:"x-acl,omitempty"`
+}
+
+// Parameter describes a path or query parameter of a route
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes the payload accepted by a route
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response returned by a route
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType associates a schema to a content type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// NewDocument create an OpenAPI document without any path
+func NewDocument(title, version string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]PathItem),
+	}
+}
+
+// AddOperation add the operation to the document under the given method and chi route pattern.
+// Path parameters found in the pattern that are not described by the operation are added to it
+func (d *Document) AddOperation(method, pattern string, op *Operation) {
+	path, params := ConvertPath(pattern)
+
+	for _, name := range params {
+		if !hasParameter(op.Parameters, name, InPath) {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: InPath, Schema: &Schema{Type: TypeString}})
+		}
+	}
+	for i := range op.Parameters {
+		if op.Parameters[i].In == InPath {
+			// path parameters are always required by the specification
+			op.Parameters[i].Required = true
+		}
+		if op.Parameters[i].Schema == nil {
+			op.Parameters[i].Schema = &Schema{Type: TypeString}
+		}
+	}
+
+	if len(op.Responses) == 0 {
+		op.Responses = map[string]Response{"default": {Description: "default response"}}
+	}
+
+	if _, found := d.Paths[path]; !found {
+		d.Paths[path] = make(PathItem)
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// ConvertPath transforms a chi route pattern into an OpenAPI path,
+// returning it together with the names of its path parameters
+func ConvertPath(pattern string) (string, []string) {
+	params := make([]string, 0)
+
+	path := chiParamRegex.ReplaceAllStringFunc(pattern, func(match string) string {
+		name := chiParamRegex.FindStringSubmatch(match)[1]
+		params = append(params, name)
+		return "{" + name + "}"
+	})
+
+	return path, params
+}
+
+// JSONRequestBody describes a JSON payload whose schema is derived from the given value
+func JSONRequestBody(body interface{}) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{jsonContentType: {Schema: SchemaOf(body)}},
+	}
+}
+
+// JSONResponses describes the JSON responses of a route, whose schemas are derived from the values
+// associated with each status code. A nil value describes a response without body
+func JSONResponses(responses map[int]interface{}) map[string]Response {
+	result := make(map[string]Response, len(responses))
+
+	for statusCode, body := range responses {
+		response := Response{Description: describeStatus(statusCode)}
+		if body != nil {
+			response.Content = map[string]MediaType{jsonContentType: {Schema: SchemaOf(body)}}
+		}
+		result[strconv.Itoa(statusCode)] = response
+	}
+
+	return result
+}
+
+func describeStatus(statusCode int) string {
+	if text := http.StatusText(statusCode); text != "" {
+		return text
+	}
+
+	return "response"
+}
+
+func hasParameter(params []Parameter, name, in string) bool {
+	for _, param := range params {
+		if param.Name == name && param.In == in {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,92 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertPath(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		path     string
+		params   []string
+		testName string
+	}{
+		{"/orders", "/orders", []string{}, "path without params"},
+		{"/orders/{id}", "/orders/{id}", []string{"id"}, "path with a single param"},
+		{"/orders/{id:[0-9]+}/items/{item}", "/orders/{id}/items/{item}", []string{"id", "item"}, "path with regexp params"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			path, params := ConvertPath(tc.pattern)
+
+			require.Equal(t, tc.path, path)
+			require.Equal(t, tc.params, params)
+		})
+	}
+}
+
+func TestDocument(t *testing.T) {
+	type order struct {
+		ID string `json:"id"`
+	}
+
+	t.Run("add operations to document paths", func(t *testing.T) {
+		doc := NewDocument("orders", "v1.0.0")
+
+		doc.AddOperation(http.MethodGet, "/orders/{id}", &Operation{
+			Summary:   "get an order",
+			Responses: JSONResponses(map[int]interface{}{http.StatusOK: order{}, http.StatusNotFound: nil}),
+		})
+		doc.AddOperation(http.MethodPost, "/orders", &Operation{RequestBody: JSONRequestBody(order{})})
+
+		require.Equal(t, Version, doc.OpenAPI)
+		require.Equal(t, Info{Title: "orders", Version: "v1.0.0"}, doc.Info)
+		require.Len(t, doc.Paths, 2)
+
+		getOrder := doc.Paths["/orders/{id}"]["get"]
+		require.Equal(t, "get an order", getOrder.Summary)
+		require.Equal(t, []Parameter{{Name: "id", In: InPath, Required: true, Schema: &Schema{Type: TypeString}}}, getOrder.Parameters)
+		require.Equal(t, "OK", getOrder.Responses["200"].Description)
+		require.Equal(t, TypeObject, getOrder.Responses["200"].Content["application/json"].Schema.Type)
+		require.Empty(t, getOrder.Responses["404"].Content)
+
+		createOrder := doc.Paths["/orders"]["post"]
+		require.True(t, createOrder.RequestBody.Required)
+		require.Contains(t, createOrder.Responses, "default", "operations always describe a response")
+	})
+
+	t.Run("keep path parameters descriptions", func(t *testing.T) {
+		doc := NewDocument("orders", "v1.0.0")
+
+		doc.AddOperation(http.MethodDelete, "/orders/{id}", &Operation{
+			Parameters: []Parameter{
+				{Name: "id", In: InPath, Description: "order identifier"},
+				{Name: "force", In: InQuery},
+			},
+		})
+
+		params := doc.Paths["/orders/{id}"]["delete"].Parameters
+		require.Len(t, params, 2)
+		require.Equal(t, "order identifier", params[0].Description)
+		require.True(t, params[0].Required)
+		require.False(t, params[1].Required)
+	})
+
+	t.Run("serialize document as JSON", func(t *testing.T) {
+		doc := NewDocument("orders", "v1.0.0")
+		doc.AddOperation(http.MethodGet, "/orders", &Operation{})
+
+		body, err := json.Marshal(doc)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"openapi": "3.0.3",
+			"info": {"title": "orders", "version": "v1.0.0"},
+			"paths": {"/orders": {"get": {"responses": {"default": {"description": "default response"}}}}}
+		}`, string(body))
+	})
+}
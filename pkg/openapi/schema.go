@@ -0,0 +1,169 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSON Schema types employed by OpenAPI documents
+const (
+	TypeArray   = "array"
+	TypeBoolean = "boolean"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeObject  = "object"
+	TypeString  = "string"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Schema represents the subset of the OpenAPI schema object
+// that can be derived from Go types
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// SchemaOf derive the schema of the given value from its Go type.
+// Struct fields are described following their json tags: fields without the omitempty
+// option are required, while the description tag provides the property description
+func SchemaOf(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+
+	return schemaOf(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if t.Kind() == reflect.Ptr {
+		schema := schemaOf(t.Elem(), visiting)
+		schema.Nullable = true
+		return schema
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: TypeString, Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType):
+		// custom serialization can not be inferred
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: TypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: TypeInteger}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: TypeInteger, Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: TypeInteger, Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: TypeNumber, Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: TypeNumber, Format: "double"}
+	case reflect.String:
+		return &Schema{Type: TypeString}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// byte slices are encoded as base64 strings
+			return &Schema{Type: TypeString, Format: "byte"}
+		}
+		return &Schema{Type: TypeArray, Items: schemaOf(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: TypeObject, AdditionalProperties: schemaOf(t.Elem(), visiting)}
+	case reflect.Struct:
+		return structSchema(t, visiting)
+	default:
+		// interfaces and other kinds accept any value
+		return &Schema{}
+	}
+}
+
+func structSchema(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	schema := &Schema{Type: TypeObject}
+	// recursive types are described as generic objects
+	if visiting[t] {
+		return schema
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	schema.Properties = make(map[string]*Schema)
+	addStructFields(schema, t, visiting)
+
+	return schema
+}
+
+func addStructFields(schema *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, skip := jsonField(field)
+		if skip {
+			continue
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			// embedded structs fields are promoted to the parent object
+			if fieldType.Kind() == reflect.Struct {
+				addStructFields(schema, fieldType, visiting)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaOf(field.Type, visiting)
+		if description := field.Tag.Get("description"); description != "" {
+			property.Description = description
+		}
+		schema.Properties[name] = property
+
+		if !omitempty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// jsonField return the name and the omitempty option of a struct field
+// following the encoding/json rules, and whether the field is not serialized
+func jsonField(field reflect.StructField) (string, bool, bool) {
+	if field.PkgPath != "" && !field.Anonymous {
+		return "", false, true
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	omitempty := false
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitempty = true
+		}
+	}
+
+	return parts[0], omitempty, false
+}
@@ -0,0 +1,76 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type audit struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type customer struct {
+	audit
+	Name     string            `json:"name" description:"customer full name"`
+	Age      int64             `json:"age,omitempty"`
+	Score    float64           `json:"score"`
+	Active   bool              `json:"active"`
+	Tags     []string          `json:"tags,omitempty"`
+	Avatar   []byte            `json:"avatar,omitempty"`
+	Address  *address          `json:"address,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Extra    interface{}       `json:"extra,omitempty"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Referrer *customer         `json:"referrer,omitempty"`
+	Internal string            `json:"-"`
+	secret   string
+	NoTag    string
+}
+
+func TestSchemaOf(t *testing.T) {
+	t.Run("describe primitive types", func(t *testing.T) {
+		require.Equal(t, &Schema{Type: TypeString}, SchemaOf(""))
+		require.Equal(t, &Schema{Type: TypeBoolean}, SchemaOf(true))
+		require.Equal(t, &Schema{Type: TypeInteger}, SchemaOf(1))
+		require.Equal(t, &Schema{Type: TypeInteger, Format: "int32"}, SchemaOf(int32(1)))
+		require.Equal(t, &Schema{Type: TypeNumber, Format: "double"}, SchemaOf(1.5))
+		require.Equal(t, &Schema{Type: TypeString, Format: "date-time"}, SchemaOf(time.Now()))
+		require.Equal(t, &Schema{}, SchemaOf(nil))
+	})
+
+	t.Run("describe collections", func(t *testing.T) {
+		require.Equal(t, &Schema{Type: TypeArray, Items: &Schema{Type: TypeInteger}}, SchemaOf([]int{}))
+		require.Equal(t, &Schema{Type: TypeObject, AdditionalProperties: &Schema{Type: TypeBoolean}}, SchemaOf(map[string]bool{}))
+	})
+
+	t.Run("describe structs following json tags", func(t *testing.T) {
+		schema := SchemaOf(customer{})
+
+		require.Equal(t, TypeObject, schema.Type)
+		require.ElementsMatch(t, []string{"createdAt", "name", "score", "active", "NoTag"}, schema.Required)
+		require.Len(t, schema.Properties, 13)
+
+		require.Equal(t, &Schema{Type: TypeString, Description: "customer full name"}, schema.Properties["name"])
+		require.Equal(t, &Schema{Type: TypeString, Format: "date-time"}, schema.Properties["createdAt"], "embedded fields are promoted")
+		require.Equal(t, &Schema{Type: TypeString, Format: "byte"}, schema.Properties["avatar"])
+		require.Equal(t, &Schema{
+			Type:       TypeObject,
+			Nullable:   true,
+			Properties: map[string]*Schema{"city": {Type: TypeString}},
+			Required:   []string{"city"},
+		}, schema.Properties["address"])
+		require.Equal(t, &Schema{}, schema.Properties["extra"])
+		require.Equal(t, &Schema{}, schema.Properties["raw"])
+		require.Equal(t, &Schema{Type: TypeObject, Nullable: true}, schema.Properties["referrer"], "recursive types are not expanded")
+
+		require.NotContains(t, schema.Properties, "Internal")
+		require.NotContains(t, schema.Properties, "secret")
+	})
+}
@@ -0,0 +1,161 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mia-platform/configlib"
+)
+
+// Default names of the headers set by Mia-Platform gateway
+const (
+	DefaultUserIDHeader         = "miauserid"
+	DefaultUserGroupsHeader     = "miausergroups"
+	DefaultUserPropertiesHeader = "miauserproperties"
+	DefaultClientTypeHeader     = "client-type"
+	DefaultBackOfficeHeader     = "isbackoffice"
+)
+
+type contextKey struct{}
+
+// HeaderKeys defines the names of the headers carrying the platform user details
+type HeaderKeys struct {
+	UserID         string
+	UserGroups     string
+	UserProperties string
+	ClientType     string
+	BackOffice     string
+}
+
+// HeaderKeysEnvConfig lists the environment variables employed by Mia-Platform
+// to override the platform headers names. It can be employed to load HeaderKeys
+// with configlib, falling back to default names when variables are not set
+var HeaderKeysEnvConfig = []configlib.EnvConfig{
+	{Key: "USERID_HEADER_KEY", Variable: "UserID", DefaultValue: DefaultUserIDHeader},
+	{Key: "GROUPS_HEADER_KEY", Variable: "UserGroups", DefaultValue: DefaultUserGroupsHeader},
+	{Key: "USER_PROPERTIES_HEADER_KEY", Variable: "UserProperties", DefaultValue: DefaultUserPropertiesHeader},
+	{Key: "CLIENTTYPE_HEADER_KEY", Variable: "ClientType", DefaultValue: DefaultClientTypeHeader},
+	{Key: "BACKOFFICE_HEADER_KEY", Variable: "BackOffice", DefaultValue: DefaultBackOfficeHeader},
+}
+
+// WithDefaults returns a copy of the header keys where missing names are replaced by the default ones
+func (hk HeaderKeys) WithDefaults() HeaderKeys {
+	if hk.UserID == "" {
+		hk.UserID = DefaultUserIDHeader
+	}
+	if hk.UserGroups == "" {
+		hk.UserGroups = DefaultUserGroupsHeader
+	}
+	if hk.UserProperties == "" {
+		hk.UserProperties = DefaultUserPropertiesHeader
+	}
+	if hk.ClientType == "" {
+		hk.ClientType = DefaultClientTypeHeader
+	}
+	if hk.BackOffice == "" {
+		hk.BackOffice = DefaultBackOfficeHeader
+	}
+
+	return hk
+}
+
+// Names returns the names of the platform headers
+func (hk HeaderKeys) Names() []string {
+	return []string{hk.UserID, hk.UserGroups, hk.UserProperties, hk.ClientType, hk.BackOffice}
+}
+
+// PlatformUser represents the user details that Mia-Platform gateway forwards to the service
+type PlatformUser struct {
+	// ID is the identifier of the user performing the request
+	ID string
+	// Groups lists the groups the user belongs to
+	Groups []string
+	// Properties holds the user properties, decoded from their JSON representation
+	Properties map[string]interface{}
+	// ClientType identifies the client application performing the request
+	ClientType string
+	// IsBackOffice reports whether the request comes from the backoffice
+	IsBackOffice bool
+}
+
+// HasGroup reports whether the user belongs to the given group
+func (pu PlatformUser) HasGroup(group string) bool {
+	for _, g := range pu.Groups {
+		if g == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseUser reads the platform user details from the request headers
+func ParseUser(header http.Header, keys HeaderKeys) PlatformUser {
+	user := PlatformUser{
+		ID:         header.Get(keys.UserID),
+		Groups:     make([]string, 0),
+		Properties: make(map[string]interface{}),
+		ClientType: header.Get(keys.ClientType),
+	}
+
+	for _, group := range strings.Split(header.Get(keys.UserGroups), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			user.Groups = append(user.Groups, group)
+		}
+	}
+
+	if properties := header.Get(keys.UserProperties); properties != "" {
+		// malformed properties are ignored, since they are set by the gateway
+		_ = json.Unmarshal([]byte(properties), &user.Properties)
+	}
+
+	user.IsBackOffice, _ = strconv.ParseBool(header.Get(keys.BackOffice))
+
+	return user
+}
+
+// Middleware returns an http middleware that parses the platform headers
+// of each incoming request and stores the resulting PlatformUser in its context
+func Middleware(keys HeaderKeys) func(http.Handler) http.Handler {
+	keys = keys.WithDefaults()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := ParseUser(r.Header, keys)
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// WithUser returns a copy of the context holding the platform user
+func WithUser(ctx context.Context, user PlatformUser) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// User returns the platform user stored in the context
+// and whether it has been found
+func User(ctx context.Context) (PlatformUser, bool) {
+	user, ok := ctx.Value(contextKey{}).(PlatformUser)
+	return user, ok
+}
+
+// UserID returns the identifier of the platform user stored in the context
+func UserID(ctx context.Context) string {
+	user, _ := User(ctx)
+	return user.ID
+}
+
+// UserGroups returns the groups of the platform user stored in the context
+func UserGroups(ctx context.Context) []string {
+	user, _ := User(ctx)
+	return user.Groups
+}
+
+// IsBackOffice reports whether the request whose context is provided comes from the backoffice
+func IsBackOffice(ctx context.Context) bool {
+	user, _ := User(ctx)
+	return user.IsBackOffice
+}
@@ -0,0 +1,105 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mia-platform/configlib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUser(t *testing.T) {
+	t.Run("read user details from default headers", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("miauserid", "user-1")
+		header.Set("miausergroups", "admin, support,,")
+		header.Set("miauserproperties", `{"name":"Mario","age":42}`)
+		header.Set("client-type", "backoffice-app")
+		header.Set("isbackoffice", "true")
+
+		user := ParseUser(header, HeaderKeys{}.WithDefaults())
+
+		require.Equal(t, PlatformUser{
+			ID:           "user-1",
+			Groups:       []string{"admin", "support"},
+			Properties:   map[string]interface{}{"name": "Mario", "age": float64(42)},
+			ClientType:   "backoffice-app",
+			IsBackOffice: true,
+		}, user)
+		require.True(t, user.HasGroup("support"))
+		require.False(t, user.HasGroup("guest"))
+	})
+
+	t.Run("missing and malformed headers result in empty values", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("miauserproperties", "not-a-json")
+		header.Set("isbackoffice", "maybe")
+
+		user := ParseUser(header, HeaderKeys{}.WithDefaults())
+
+		require.Equal(t, "", user.ID)
+		require.Empty(t, user.Groups)
+		require.Empty(t, user.Properties)
+		require.False(t, user.IsBackOffice)
+	})
+
+	t.Run("read user details from custom headers", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("x-user", "user-2")
+		header.Set("miauserid", "ignored")
+
+		user := ParseUser(header, HeaderKeys{UserID: "x-user"}.WithDefaults())
+
+		require.Equal(t, "user-2", user.ID)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("store platform user in request context", func(t *testing.T) {
+		var user PlatformUser
+		var found bool
+		handler := Middleware(HeaderKeys{UserGroups: "x-groups"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, found = User(r.Context())
+			require.Equal(t, "user-1", UserID(r.Context()))
+			require.Equal(t, []string{"admin"}, UserGroups(r.Context()))
+			require.False(t, IsBackOffice(r.Context()))
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("miauserid", "user-1")
+		req.Header.Set("x-groups", "admin")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.True(t, found)
+		require.Equal(t, "user-1", user.ID)
+	})
+
+	t.Run("accessors return empty values when user is missing", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		_, found := User(req.Context())
+		require.False(t, found)
+		require.Equal(t, "", UserID(req.Context()))
+		require.Empty(t, UserGroups(req.Context()))
+		require.False(t, IsBackOffice(req.Context()))
+	})
+}
+
+func TestHeaderKeysEnvConfig(t *testing.T) {
+	t.Run("load header names from environment", func(t *testing.T) {
+		t.Setenv("USERID_HEADER_KEY", "x-user-id")
+		t.Setenv("BACKOFFICE_HEADER_KEY", "x-backoffice")
+
+		var keys HeaderKeys
+		require.NoError(t, configlib.GetEnvVariables(HeaderKeysEnvConfig, &keys))
+
+		require.Equal(t, HeaderKeys{
+			UserID:         "x-user-id",
+			UserGroups:     DefaultUserGroupsHeader,
+			UserProperties: DefaultUserPropertiesHeader,
+			ClientType:     DefaultClientTypeHeader,
+			BackOffice:     "x-backoffice",
+		}, keys)
+	})
+}
@@ -0,0 +1,73 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// DefaultHeader is the header carrying the identifier of the request,
+// which is read by the request logger as well
+const DefaultHeader = "x-request-id"
+
+// maxLength is the maximum length of the identifiers accepted from the incoming requests
+const maxLength = 128
+
+type contextKey struct{}
+
+// Middleware return a http middleware that assigns an identifier to each request,
+// reading it from the header or generating a new one when it is missing or not valid.
+// The identifier is stored in the request context, set in the request header, so that
+// it is logged and forwarded to other services, and echoed in the response header
+func Middleware(header string) func(http.Handler) http.Handler {
+	if header == "" {
+		header = DefaultHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if !valid(id) {
+				id = uuid.NewString()
+			}
+
+			r.Header.Set(header, id)
+			// the request logger reads the identifier from the default header only
+			if !strings.EqualFold(header, DefaultHeader) {
+				r.Header.Set(DefaultHeader, id)
+			}
+			w.Header().Set(header, id)
+
+			next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+		})
+	}
+}
+
+// WithID returns a copy of the context holding the request identifier
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identifier of the request stored in the context,
+// or an empty string when the context does not hold any
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// valid reports whether the identifier is not empty and made of printable ASCII characters,
+// so that it can be safely logged and forwarded
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
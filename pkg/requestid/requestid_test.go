@@ -0,0 +1,70 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	// serve executes the middleware, returning the response and the id available to the handler
+	serve := func(header string, incoming map[string]string) (*httptest.ResponseRecorder, *http.Request) {
+		var served *http.Request
+		handler := Middleware(header)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = r
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for key, value := range incoming {
+			req.Header.Set(key, value)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr, served
+	}
+
+	t.Run("honour the incoming request id", func(t *testing.T) {
+		rr, served := serve("", map[string]string{"x-request-id": "req-1"})
+
+		require.Equal(t, "req-1", FromContext(served.Context()))
+		require.Equal(t, "req-1", rr.Header().Get(DefaultHeader))
+	})
+
+	t.Run("generate the request id when missing", func(t *testing.T) {
+		rr, served := serve("", nil)
+
+		id := FromContext(served.Context())
+		_, err := uuid.Parse(id)
+		require.NoError(t, err)
+		require.Equal(t, id, rr.Header().Get(DefaultHeader))
+		require.Equal(t, id, served.Header.Get(DefaultHeader))
+	})
+
+	t.Run("replace request ids that are not valid", func(t *testing.T) {
+		for _, id := range []string{"req\n1", strings.Repeat("a", maxLength+1)} {
+			_, served := serve("", map[string]string{"x-request-id": id})
+			require.NotEqual(t, id, FromContext(served.Context()))
+			require.NotEmpty(t, FromContext(served.Context()))
+		}
+	})
+
+	t.Run("read the request id from a custom header", func(t *testing.T) {
+		rr, served := serve("x-correlation-id", map[string]string{"x-correlation-id": "corr-1", "x-request-id": "req-1"})
+
+		require.Equal(t, "corr-1", FromContext(served.Context()))
+		require.Equal(t, "corr-1", rr.Header().Get("x-correlation-id"))
+		require.Equal(t, "corr-1", served.Header.Get(DefaultHeader), "request logger reads the default header")
+		require.Empty(t, rr.Header().Get(DefaultHeader))
+	})
+}
+
+func TestFromContext(t *testing.T) {
+	require.Empty(t, FromContext(context.Background()))
+	require.Equal(t, "req-1", FromContext(WithID(context.Background(), "req-1")))
+}
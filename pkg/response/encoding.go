@@ -0,0 +1,191 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Content types of the encoders registered by default
+const (
+	ContentTypeJSON        = "application/json"
+	ContentTypeNDJSON      = "application/x-ndjson"
+	ContentTypeCSV         = "text/csv"
+	ContentTypeXML         = "application/xml"
+	ContentTypeMessagePack = "application/msgpack"
+)
+
+// EncoderFunc writes the value into w, encoded in a specific format
+type EncoderFunc func(w io.Writer, v interface{}) error
+
+// EncodeJSON writes the value as JSON
+func EncodeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// EncodeNDJSON writes each item of slices and arrays as a JSON line,
+// while any other value is written as a single line
+func EncodeNDJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return encoder.Encode(v)
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		if err := encoder.Encode(value.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EncodeXML writes the value as XML
+func EncodeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// EncodeMessagePack writes the value as MessagePack, naming struct fields after their json tags
+func EncodeMessagePack(w io.Writer, v interface{}) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+
+	return encoder.Encode(v)
+}
+
+// EncodeCSV writes a slice of records as CSV. Records can be string slices, written as they are,
+// structs, whose header lists the exported fields named after their json tags,
+// or maps with string keys, whose header lists their sorted keys
+func EncodeCSV(w io.Writer, v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return fmt.Errorf("csv encoding requires a slice of records, not %T", v)
+	}
+
+	rows, err := csvRows(value)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("csv records can not be written: %w", err)
+	}
+
+	return nil
+}
+
+func csvRows(records reflect.Value) ([][]string, error) {
+	elemType := records.Type().Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	switch {
+	case elemType.Kind() == reflect.Slice && elemType.Elem().Kind() == reflect.String:
+		rows := make([][]string, 0, records.Len())
+		for i := 0; i < records.Len(); i++ {
+			row := make([]string, records.Index(i).Len())
+			for j := range row {
+				row[j] = records.Index(i).Index(j).String()
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case elemType.Kind() == reflect.Struct:
+		return structRows(records, elemType), nil
+	case elemType.Kind() == reflect.Map && elemType.Key().Kind() == reflect.String:
+		return mapRows(records), nil
+	default:
+		return nil, fmt.Errorf("csv encoding does not support records of type %s", elemType)
+	}
+}
+
+func structRows(records reflect.Value, recordType reflect.Type) [][]string {
+	header := make([]string, 0, recordType.NumField())
+	fields := make([]int, 0, recordType.NumField())
+	for i := 0; i < recordType.NumField(); i++ {
+		field := recordType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	rows := [][]string{header}
+	for i := 0; i < records.Len(); i++ {
+		record := reflect.Indirect(records.Index(i))
+		row := make([]string, 0, len(fields))
+		for _, field := range fields {
+			row = append(row, csvValue(record, field))
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func csvValue(record reflect.Value, field int) string {
+	if !record.IsValid() {
+		return ""
+	}
+
+	return fmt.Sprint(record.Field(field).Interface())
+}
+
+func mapRows(records reflect.Value) [][]string {
+	keys := make(map[string]bool)
+	for i := 0; i < records.Len(); i++ {
+		record := reflect.Indirect(records.Index(i))
+		if !record.IsValid() {
+			continue
+		}
+		for _, key := range record.MapKeys() {
+			keys[key.String()] = true
+		}
+	}
+
+	header := make([]string, 0, len(keys))
+	for key := range keys {
+		header = append(header, key)
+	}
+	sort.Strings(header)
+
+	rows := [][]string{header}
+	for i := 0; i < records.Len(); i++ {
+		record := reflect.Indirect(records.Index(i))
+		row := make([]string, 0, len(header))
+		for _, key := range header {
+			var value reflect.Value
+			if record.IsValid() {
+				value = record.MapIndex(reflect.ValueOf(key).Convert(record.Type().Key()))
+			}
+			if value.IsValid() {
+				row = append(row, fmt.Sprint(value.Interface()))
+			} else {
+				row = append(row, "")
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
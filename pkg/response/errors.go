@@ -7,30 +7,52 @@ import (
 	zpstd "github.com/danibix95/zeropino/middlewares/std"
 )
 
+// errorMessage is the legacy body of error responses
 type errorMessage struct {
-	Msg  string `json:"message"`
-	Code int    `json:"code,omitempty"`
+	Msg       string       `json:"message"`
+	Code      int          `json:"code,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
-// NotFound is an http handler that return a JSON response
+// FieldError describes why a field of the incoming request is not valid
+type FieldError struct {
+	// Field is the name of the invalid field
+	Field string `json:"field"`
+	// In is the part of the request containing the field, such as body, path or query
+	In string `json:"in,omitempty"`
+	// Message explains why the field is not valid
+	Message string `json:"message"`
+}
+
+// BadRequest write an error response reporting that the incoming request is not valid,
+// listing the reason why each invalid field has been rejected
+func BadRequest(rw http.ResponseWriter, r *http.Request, message string, fieldErrors ...FieldError) {
+	WriteError(rw, r, &ValidationError{Detail: message, Fields: fieldErrors})
+}
+
+// Forbidden write an error response reporting that the user
+// performing the incoming request is not allowed to access the resource
+func Forbidden(rw http.ResponseWriter, r *http.Request, message string) {
+	WriteProblem(rw, r, NewProblem(http.StatusForbidden, message))
+}
+
+// NotFound is an http handler that return an error response
 // when requested resource is not found at the current route
 func NotFound(rw http.ResponseWriter, r *http.Request) {
-	rw.WriteHeader(http.StatusNotFound)
-	JSON(rw, errorMessage{Msg: "Route not found"})
+	WriteProblem(rw, r, NewProblem(http.StatusNotFound, "Route not found"))
 }
 
-// MethodNotAllowed is an http handler that return a JSON response
+// MethodNotAllowed is an http handler that return an error response
 // when the method of current request has not been defined for current route
 func MethodNotAllowed(rw http.ResponseWriter, r *http.Request) {
-	rw.WriteHeader(http.StatusMethodNotAllowed)
-	JSON(rw, errorMessage{Msg: "Method not allowed"})
+	WriteProblem(rw, r, NewProblem(http.StatusMethodNotAllowed, "Method not allowed"))
 }
 
-// InternalServerError is an http handler that returns a JSON response
+// InternalServerError is an http handler that returns an error response
 // when an error that can not be managed by the handler is encountered during requests handling
 func InternalServerError(rw http.ResponseWriter, r *http.Request) {
-	rw.WriteHeader(http.StatusInternalServerError)
-	JSON(rw, errorMessage{Msg: "Generic server error"})
+	WriteProblem(rw, r, NewProblem(http.StatusInternalServerError, "Generic server error"))
 }
 
 // PanicManager return a middleware function that recover service from
@@ -0,0 +1,81 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the non standard status code reporting
+// that the client closed the connection before the response was sent
+const StatusClientClosedRequest = 499
+
+// ErrorMapper converts the errors returned by handlers into problem details.
+// Its rules are evaluated in registration order; when none applies, errors providing
+// their problem details are rendered as such, canceled contexts are reported with
+// StatusClientClosedRequest, expired deadlines as 504 - Gateway Timeout and
+// any other error as 500 - Internal Server Error
+type ErrorMapper struct {
+	rules []func(err error) *Problem
+}
+
+// NewErrorMapper creates an error mapper without rules, which applies only the default conversions
+func NewErrorMapper() *ErrorMapper {
+	return new(ErrorMapper)
+}
+
+// Func add a rule converting errors into problem details. The rule returns nil when it does not apply
+func (m *ErrorMapper) Func(rule func(err error) *Problem) *ErrorMapper {
+	m.rules = append(m.rules, rule)
+	return m
+}
+
+// Sentinel add a rule converting the errors matching target, as reported by errors.Is,
+// into the problem details with the given status code and detail
+func (m *ErrorMapper) Sentinel(target error, status int, detail string) *ErrorMapper {
+	return m.Func(func(err error) *Problem {
+		if errors.Is(err, target) {
+			return NewProblem(status, detail)
+		}
+		return nil
+	})
+}
+
+// MapAs add to the mapper a rule converting the errors of type T, as found by errors.As, into problem details
+func MapAs[T error](m *ErrorMapper, convert func(err T) *Problem) *ErrorMapper {
+	return m.Func(func(err error) *Problem {
+		var target T
+		if errors.As(err, &target) {
+			return convert(target)
+		}
+		return nil
+	})
+}
+
+// Problem returns the problem details describing err. A nil mapper applies only the default conversions
+func (m *ErrorMapper) Problem(err error) *Problem {
+	if m != nil {
+		for _, rule := range m.rules {
+			if problem := rule(err); problem != nil {
+				return problem
+			}
+		}
+	}
+
+	var problemErr ProblemError
+	switch {
+	case errors.As(err, &problemErr):
+		return problemErr.Problem()
+	case errors.Is(err, context.Canceled):
+		return &Problem{Status: StatusClientClosedRequest, Title: "Client Closed Request", Detail: "request canceled by the client"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewProblem(http.StatusGatewayTimeout, "request timed out")
+	default:
+		return NewProblem(http.StatusInternalServerError, "Generic server error")
+	}
+}
+
+// WriteError writes the error response, in the format selected for the request, describing err
+func (m *ErrorMapper) WriteError(rw http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(rw, r, m.Problem(err))
+}
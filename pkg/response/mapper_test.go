@@ -0,0 +1,69 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type quotaError struct {
+	limit int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("quota of %d requests exceeded", e.limit)
+}
+
+func TestErrorMapper(t *testing.T) {
+	errLocked := errors.New("resource locked")
+
+	mapper := NewErrorMapper().
+		Sentinel(errLocked, http.StatusLocked, "the order is being processed")
+	MapAs(mapper, func(err *quotaError) *Problem {
+		return &Problem{Status: http.StatusTooManyRequests, Detail: err.Error(), Extensions: map[string]interface{}{"limit": err.limit}}
+	})
+	mapper.Func(func(err error) *Problem {
+		if err.Error() == "teapot" {
+			return NewProblem(http.StatusTeapot, "")
+		}
+		return nil
+	})
+
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+	}{
+		{name: "wrapped sentinel error", err: fmt.Errorf("updating order: %w", errLocked), status: http.StatusLocked, detail: "the order is being processed"},
+		{name: "typed error", err: fmt.Errorf("calling api: %w", &quotaError{limit: 10}), status: http.StatusTooManyRequests, detail: "quota of 10 requests exceeded"},
+		{name: "custom rule", err: errors.New("teapot"), status: http.StatusTeapot},
+		{name: "error providing problem details", err: &ConflictError{Detail: "order already exists"}, status: http.StatusConflict, detail: "order already exists"},
+		{name: "canceled context", err: fmt.Errorf("querying orders: %w", context.Canceled), status: StatusClientClosedRequest, detail: "request canceled by the client"},
+		{name: "expired deadline", err: fmt.Errorf("querying orders: %w", context.DeadlineExceeded), status: http.StatusGatewayTimeout, detail: "request timed out"},
+		{name: "unknown error", err: errors.New("connection refused"), status: http.StatusInternalServerError, detail: "Generic server error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problem := mapper.Problem(tc.err)
+			require.Equal(t, tc.status, problem.Status)
+			require.Equal(t, tc.detail, problem.Detail)
+		})
+	}
+
+	t.Run("rules take precedence over default conversions", func(t *testing.T) {
+		problem := NewErrorMapper().Sentinel(context.Canceled, http.StatusServiceUnavailable, "shutting down").Problem(context.Canceled)
+		require.Equal(t, http.StatusServiceUnavailable, problem.Status)
+	})
+
+	t.Run("nil mapper applies default conversions", func(t *testing.T) {
+		var nilMapper *ErrorMapper
+		require.Equal(t, http.StatusNotFound, nilMapper.Problem(&NotFoundError{Detail: "missing"}).Status)
+		require.Equal(t, "Client Closed Request", nilMapper.Problem(context.Canceled).Title)
+	})
+}
@@ -0,0 +1,167 @@
+package response
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	zpstd "github.com/danibix95/zeropino/middlewares/std"
+)
+
+// Encoders selects how response bodies are encoded, according to the content types
+// accepted by the client. Encoders are preferred in registration order
+type Encoders struct {
+	contentTypes []string
+	encoders     map[string]EncoderFunc
+}
+
+// NewEncoders creates an empty registry of encoders
+func NewEncoders() *Encoders {
+	return &Encoders{encoders: make(map[string]EncoderFunc)}
+}
+
+// DefaultEncoders creates a registry of the JSON, NDJSON, CSV, XML and MessagePack encoders,
+// where JSON is the preferred one
+func DefaultEncoders() *Encoders {
+	return NewEncoders().
+		Register(ContentTypeJSON, EncodeJSON).
+		Register(ContentTypeNDJSON, EncodeNDJSON).
+		Register(ContentTypeCSV, EncodeCSV).
+		Register(ContentTypeXML, EncodeXML).
+		Register(ContentTypeMessagePack, EncodeMessagePack)
+}
+
+// defaultEncoders is employed by Write and Negotiate
+var defaultEncoders = DefaultEncoders()
+
+// Register add the encoder of the content type, replacing the one already registered for it
+func (e *Encoders) Register(contentType string, encoder EncoderFunc) *Encoders {
+	contentType = strings.ToLower(contentType)
+	if _, found := e.encoders[contentType]; !found {
+		e.contentTypes = append(e.contentTypes, contentType)
+	}
+	e.encoders[contentType] = encoder
+
+	return e
+}
+
+// Negotiate returns the registered content type that is preferred by the Accept header of the request.
+// When the header is missing, the first registered content type is returned, while an empty string
+// is returned when none of the registered content types is acceptable
+func (e *Encoders) Negotiate(r *http.Request) string {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		if len(e.contentTypes) == 0 {
+			return ""
+		}
+		return e.contentTypes[0]
+	}
+
+	ranges := parseAccept(strings.Join(accept, ","))
+	selected, selectedQuality := "", 0.0
+	for _, contentType := range e.contentTypes {
+		if quality := acceptQuality(ranges, contentType); quality > selectedQuality {
+			selected, selectedQuality = contentType, quality
+		}
+	}
+
+	return selected
+}
+
+// Write encodes the body with the encoder negotiated for the request and writes it with the status code.
+// The body is encoded before writing the response, so that encoding errors are reported with
+// a 500 - Internal Server Error response, while a 406 - Not Acceptable response is returned
+// when no encoder is acceptable
+func (e *Encoders) Write(rw http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	contentType := e.Negotiate(r)
+	if contentType == "" {
+		WriteProblem(rw, r, NewProblem(http.StatusNotAcceptable, "none of the accepted content types can be produced"))
+		return
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := e.encoders[contentType](buffer, body); err != nil {
+		zpstd.Get(r.Context()).Error().Err(err).Str("contentType", contentType).Msg("response can not be encoded")
+		WriteProblem(rw, r, NewProblem(http.StatusInternalServerError, "error encoding response"))
+		return
+	}
+
+	if strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Set("Content-Length", strconv.Itoa(buffer.Len()))
+	rw.WriteHeader(status)
+	_, _ = rw.Write(buffer.Bytes())
+}
+
+// Write encodes the body in the format preferred by the Accept header of the request among
+// JSON, NDJSON, CSV, XML and MessagePack, writing it with the status code (see Encoders.Write)
+func Write(rw http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	defaultEncoders.Write(rw, r, status, body)
+}
+
+// Negotiate returns the content type preferred by the Accept header of the request
+// among JSON, NDJSON, CSV, XML and MessagePack (see Encoders.Negotiate)
+func Negotiate(r *http.Request) string {
+	return defaultEncoders.Negotiate(r)
+}
+
+// mediaRange is an item of the Accept header
+type mediaRange struct {
+	mediaType string
+	subtype   string
+	quality   float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := params["q"]; found {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		parts := strings.SplitN(mediaType, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		ranges = append(ranges, mediaRange{mediaType: parts[0], subtype: parts[1], quality: quality})
+	}
+
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific media range matching the content type
+func acceptQuality(ranges []mediaRange, contentType string) float64 {
+	parts := strings.SplitN(contentType, "/", 2)
+	quality, specificity := 0.0, -1
+	for _, r := range ranges {
+		var matchSpecificity int
+		switch {
+		case r.mediaType == parts[0] && r.subtype == parts[1]:
+			matchSpecificity = 2
+		case r.mediaType == parts[0] && r.subtype == "*":
+			matchSpecificity = 1
+		case r.mediaType == "*" && r.subtype == "*":
+			matchSpecificity = 0
+		default:
+			continue
+		}
+
+		if matchSpecificity > specificity {
+			quality, specificity = r.quality, matchSpecificity
+		}
+	}
+
+	return quality
+}
@@ -0,0 +1,206 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type order struct {
+	ID       string  `json:"id" xml:"id"`
+	Amount   float64 `json:"amount" xml:"amount"`
+	Internal string  `json:"-" xml:"-"`
+}
+
+func TestNegotiate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		accept   []string
+		expected string
+	}{
+		{name: "prefer JSON without Accept header", expected: ContentTypeJSON},
+		{name: "select the exact content type", accept: []string{"text/csv"}, expected: ContentTypeCSV},
+		{name: "ignore case and parameters", accept: []string{"Application/XML; charset=utf-8"}, expected: ContentTypeXML},
+		{name: "prefer the highest quality", accept: []string{"application/json;q=0.5, application/msgpack"}, expected: ContentTypeMessagePack},
+		{name: "break ties by registration order", accept: []string{"application/xml, text/csv, application/x-ndjson"}, expected: ContentTypeNDJSON},
+		{name: "match type wildcards", accept: []string{"text/*"}, expected: ContentTypeCSV},
+		{name: "match any content type", accept: []string{"*/*"}, expected: ContentTypeJSON},
+		{name: "prefer specific ranges to wildcards", accept: []string{"*/*, application/json;q=0"}, expected: ContentTypeNDJSON},
+		{name: "join multiple headers", accept: []string{"text/html", "application/xml;q=0.9"}, expected: ContentTypeXML},
+		{name: "skip malformed ranges", accept: []string{"invalid, application/json;q=abc, text/csv;q=0.1"}, expected: ContentTypeCSV},
+		{name: "report unacceptable content types", accept: []string{"text/html, image/*"}, expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, accept := range tc.accept {
+				req.Header.Add("Accept", accept)
+			}
+
+			require.Equal(t, tc.expected, Negotiate(req))
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	orders := []order{{ID: "1", Amount: 9.5, Internal: "secret"}, {ID: "2", Amount: 20}}
+
+	write := func(accept string, body interface{}) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		Write(rr, req, http.StatusCreated, body)
+		return rr
+	}
+
+	testCases := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{name: "JSON", accept: "application/json", contentType: ContentTypeJSON, body: `[{"id":"1","amount":9.5},{"id":"2","amount":20}]` + "\n"},
+		{name: "NDJSON", accept: "application/x-ndjson", contentType: ContentTypeNDJSON, body: `{"id":"1","amount":9.5}` + "\n" + `{"id":"2","amount":20}` + "\n"},
+		{name: "CSV", accept: "text/csv", contentType: "text/csv; charset=utf-8", body: "id,amount\n1,9.5\n2,20\n"},
+		{name: "XML", accept: "application/xml", contentType: ContentTypeXML, body: xml.Header + "<order><id>1</id><amount>9.5</amount></order><order><id>2</id><amount>20</amount></order>"},
+	}
+
+	for _, tc := range testCases {
+		t.Run("encode "+tc.name, func(t *testing.T) {
+			rr := write(tc.accept, orders)
+
+			require.Equal(t, http.StatusCreated, rr.Code)
+			require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			require.Equal(t, tc.body, rr.Body.String())
+			require.Equal(t, strconv.Itoa(rr.Body.Len()), rr.Header().Get("Content-Length"))
+		})
+	}
+
+	t.Run("encode MessagePack", func(t *testing.T) {
+		rr := write("application/msgpack", orders)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Equal(t, ContentTypeMessagePack, rr.Header().Get("Content-Type"))
+		require.Equal(t, strconv.Itoa(rr.Body.Len()), rr.Header().Get("Content-Length"))
+
+		var decoded []map[string]interface{}
+		require.NoError(t, msgpack.Unmarshal(rr.Body.Bytes(), &decoded))
+		require.Len(t, decoded, 2)
+		require.Equal(t, "1", decoded[0]["id"])
+		require.EqualValues(t, 9.5, decoded[0]["amount"])
+		require.NotContains(t, decoded[0], "Internal")
+	})
+
+	t.Run("reply not acceptable", func(t *testing.T) {
+		rr := write("text/html", orders)
+
+		require.Equal(t, http.StatusNotAcceptable, rr.Code)
+		require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"type":"about:blank","title":"Not Acceptable","status":406,"detail":"none of the accepted content types can be produced","instance":"/orders"}`, rr.Body.String())
+	})
+
+	t.Run("reply internal server error when encoding fails", func(t *testing.T) {
+		rr := write("application/json", map[string]interface{}{"callback": func() {}})
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+		require.Empty(t, rr.Header().Get("Content-Length"))
+		require.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"error encoding response","instance":"/orders"}`, rr.Body.String())
+	})
+
+	t.Run("reject CSV records that are not supported", func(t *testing.T) {
+		rr := write("text/csv", map[string]string{"id": "1"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		rr = write("text/csv", []int{1, 2})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestEncoders(t *testing.T) {
+	t.Run("register custom encoders", func(t *testing.T) {
+		encoders := NewEncoders().
+			Register("text/plain", func(w io.Writer, v interface{}) error {
+				_, err := io.WriteString(w, "plain")
+				return err
+			}).
+			Register(ContentTypeJSON, EncodeJSON)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rr := httptest.NewRecorder()
+		encoders.Write(rr, req, http.StatusOK, nil)
+
+		require.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+		require.Equal(t, "plain", rr.Body.String())
+		require.Equal(t, "5", rr.Header().Get("Content-Length"))
+	})
+
+	t.Run("replace registered encoders keeping their preference", func(t *testing.T) {
+		failure := errors.New("not available")
+		encoders := DefaultEncoders().Register(ContentTypeJSON, func(w io.Writer, v interface{}) error {
+			return failure
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		require.Equal(t, ContentTypeJSON, encoders.Negotiate(req))
+
+		rr := httptest.NewRecorder()
+		encoders.Write(rr, req, http.StatusOK, "hello")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("no encoder is registered", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		require.Empty(t, NewEncoders().Negotiate(req))
+	})
+
+	t.Run("encode CSV from string slices and maps", func(t *testing.T) {
+		buffer := new(bytes.Buffer)
+		require.NoError(t, EncodeCSV(buffer, [][]string{{"id", "name"}, {"1", "with, comma"}}))
+		require.Equal(t, "id,name\n1,\"with, comma\"\n", buffer.String())
+
+		buffer.Reset()
+		require.NoError(t, EncodeCSV(buffer, []map[string]interface{}{{"b": 2, "a": 1}, {"c": true}}))
+		require.Equal(t, "a,b,c\n1,2,\n,,true\n", buffer.String())
+
+		buffer.Reset()
+		require.NoError(t, EncodeCSV(buffer, []*map[string]string{{"a": "1"}, nil}))
+		require.Equal(t, "a\n1\n\n", buffer.String())
+
+		buffer.Reset()
+		require.NoError(t, EncodeCSV(buffer, []*order{{ID: "1", Amount: 2}, nil}))
+		require.Equal(t, "id,amount\n1,2\n,\n", buffer.String())
+	})
+}
+
+func TestJSON(t *testing.T) {
+	t.Run("write the encoded body", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		JSON(rr, map[string]string{"message": "hello"})
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"message":"hello"}`, rr.Body.String())
+	})
+
+	t.Run("write only the error when encoding fails", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		JSON(rr, []interface{}{"partial", func() {}})
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.True(t, json.Valid(rr.Body.Bytes()))
+		require.JSONEq(t, `{"message":"error encoding response"}`, rr.Body.String())
+	})
+}
@@ -0,0 +1,259 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/danibix95/miabase/pkg/requestid"
+)
+
+// ProblemContentType is the content type of the error responses rendered as RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// defaultProblemType is the problem type that conveys no further semantics than the status code
+const defaultProblemType = "about:blank"
+
+// ErrorFormat selects the body of the error responses
+type ErrorFormat int
+
+const (
+	// ProblemFormat renders errors as RFC 7807 problem details
+	ProblemFormat ErrorFormat = iota
+	// LegacyFormat renders errors as a JSON object with message and code fields
+	LegacyFormat
+)
+
+type errorFormatKey struct{}
+
+// ErrorFormatMiddleware return a http middleware that selects the format of the error responses
+// written while handling the request. Errors are rendered as problem details when no format is selected
+func ErrorFormatMiddleware(format ErrorFormat) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithErrorFormat(r.Context(), format)))
+		})
+	}
+}
+
+// WithErrorFormat returns a copy of the context selecting the format of the error responses
+func WithErrorFormat(ctx context.Context, format ErrorFormat) context.Context {
+	return context.WithValue(ctx, errorFormatKey{}, format)
+}
+
+func errorFormat(ctx context.Context) ErrorFormat {
+	format, _ := ctx.Value(errorFormatKey{}).(ErrorFormat)
+	return format
+}
+
+// Problem describes an error as defined by RFC 7807. It can be returned as error by handlers
+type Problem struct {
+	// Type is a URI reference identifying the problem type. Defaults to about:blank
+	Type string
+	// Title is a short summary of the problem type. Defaults to the status text
+	Title string
+	// Status is the http status code of the response
+	Status int
+	// Detail explains this occurrence of the problem
+	Detail string
+	// Instance is a URI reference identifying this occurrence of the problem. Defaults to the request path
+	Instance string
+	// Extensions are additional members of the problem details
+	Extensions map[string]interface{}
+}
+
+// NewProblem creates the problem details of the given status code
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Status: status, Detail: detail}
+}
+
+// Error returns the detail of the problem, or its title when the detail is missing
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	if p.Title != "" {
+		return p.Title
+	}
+
+	return http.StatusText(p.Status)
+}
+
+// Problem returns the problem itself, so that problems can be returned as errors
+func (p *Problem) Problem() *Problem {
+	return p
+}
+
+// MarshalJSON flattens the extension members alongside the standard ones
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+
+	return json.Marshal(members)
+}
+
+// ProblemError is implemented by the errors that are rendered as problem details
+type ProblemError interface {
+	error
+	Problem() *Problem
+}
+
+// ValidationError reports that the incoming request is not valid, listing the invalid fields
+type ValidationError struct {
+	Detail string
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return e.Detail
+}
+
+// Problem returns the problem details of the error, which list the invalid fields as errors extension
+func (e *ValidationError) Problem() *Problem {
+	p := NewProblem(http.StatusBadRequest, e.Detail)
+	if len(e.Fields) > 0 {
+		p.Extensions = map[string]interface{}{"errors": e.Fields}
+	}
+
+	return p
+}
+
+// NotFoundError reports that the requested resource does not exist
+type NotFoundError struct {
+	Detail string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Detail
+}
+
+// Problem returns the problem details of the error
+func (e *NotFoundError) Problem() *Problem {
+	return NewProblem(http.StatusNotFound, e.Detail)
+}
+
+// ConflictError reports that the request conflicts with the current state of the resource
+type ConflictError struct {
+	Detail string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Detail
+}
+
+// Problem returns the problem details of the error
+func (e *ConflictError) Problem() *Problem {
+	return NewProblem(http.StatusConflict, e.Detail)
+}
+
+// UnauthorizedError reports that the request lacks valid credentials
+type UnauthorizedError struct {
+	Detail string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Detail
+}
+
+// Problem returns the problem details of the error
+func (e *UnauthorizedError) Problem() *Problem {
+	return NewProblem(http.StatusUnauthorized, e.Detail)
+}
+
+// UpstreamError reports that a service called while handling the request failed.
+// The cause is not disclosed in the response
+type UpstreamError struct {
+	Detail string
+	Err    error
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Err == nil {
+		return e.Detail
+	}
+
+	return e.Detail + ": " + e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Problem returns the problem details of the error
+func (e *UpstreamError) Problem() *Problem {
+	return NewProblem(http.StatusBadGateway, e.Detail)
+}
+
+// WriteError writes the error response, in the format selected for the request, describing err.
+// Errors that do not provide problem details are reported as internal server errors,
+// without disclosing their message (see ErrorMapper for the applied conversions)
+func WriteError(rw http.ResponseWriter, r *http.Request, err error) {
+	(*ErrorMapper)(nil).WriteError(rw, r, err)
+}
+
+// WriteProblem writes the problem details, in the format selected for the request,
+// completing them with the request path and id. Problems whose status is not a valid
+// HTTP status code are written as internal server errors
+func WriteProblem(rw http.ResponseWriter, r *http.Request, p *Problem) {
+	problem := *p
+	if problem.Status < 100 || problem.Status > 599 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Type == "" {
+		problem.Type = defaultProblemType
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" {
+		problem.Instance = r.URL.Path
+	}
+	extensions := make(map[string]interface{}, len(problem.Extensions)+1)
+	for key, value := range problem.Extensions {
+		extensions[key] = value
+	}
+	if requestID := requestid.FromContext(r.Context()); requestID != "" {
+		extensions["requestId"] = requestID
+	}
+	problem.Extensions = extensions
+
+	var body interface{} = &problem
+	contentType := ProblemContentType
+	if errorFormat(r.Context()) == LegacyFormat {
+		body = legacyMessage(problem)
+		contentType = "application/json"
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusInternalServerError)
+		_, _ = rw.Write([]byte(`{"message":"error encoding response"}`))
+		return
+	}
+
+	rw.Header().Set("Content-Type", contentType)
+	rw.WriteHeader(problem.Status)
+	_, _ = rw.Write(append(payload, '\n'))
+}
+
+// legacyMessage converts the problem into the legacy error body
+func legacyMessage(problem Problem) errorMessage {
+	message := errorMessage{Msg: problem.Error(), Code: problem.Status}
+	message.RequestID, _ = problem.Extensions["requestId"].(string)
+	message.Errors, _ = problem.Extensions["errors"].([]FieldError)
+
+	return message
+}
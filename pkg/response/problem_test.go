@@ -0,0 +1,149 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/requestid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError(t *testing.T) {
+	// write renders the error for a request to /orders/42, returning the response and its decoded body
+	write := func(t *testing.T, format ErrorFormat, err error) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodGet, "/orders/42?verbose=true", nil)
+		req = req.WithContext(WithErrorFormat(requestid.WithID(req.Context(), "req-1"), format))
+		rr := httptest.NewRecorder()
+		WriteError(rr, req, err)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		return rr, body
+	}
+
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+	}{
+		{name: "validation error", err: &ValidationError{Detail: "request is not valid"}, status: http.StatusBadRequest, detail: "request is not valid"},
+		{name: "not found error", err: &NotFoundError{Detail: "order not found"}, status: http.StatusNotFound, detail: "order not found"},
+		{name: "conflict error", err: &ConflictError{Detail: "order already exists"}, status: http.StatusConflict, detail: "order already exists"},
+		{name: "unauthorized error", err: &UnauthorizedError{Detail: "token expired"}, status: http.StatusUnauthorized, detail: "token expired"},
+		{name: "upstream error", err: &UpstreamError{Detail: "payments unavailable", Err: errors.New("connection refused")}, status: http.StatusBadGateway, detail: "payments unavailable"},
+		{name: "wrapped error", err: fmt.Errorf("loading order: %w", &NotFoundError{Detail: "order not found"}), status: http.StatusNotFound, detail: "order not found"},
+		{name: "error without problem details", err: errors.New("database password is wrong"), status: http.StatusInternalServerError, detail: "Generic server error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr, body := write(t, ProblemFormat, tc.err)
+
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+			require.Equal(t, map[string]interface{}{
+				"type":      "about:blank",
+				"title":     http.StatusText(tc.status),
+				"status":    float64(tc.status),
+				"detail":    tc.detail,
+				"instance":  "/orders/42",
+				"requestId": "req-1",
+			}, body)
+
+			rr, body = write(t, LegacyFormat, tc.err)
+
+			require.Equal(t, tc.status, rr.Code)
+			require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+			require.Equal(t, map[string]interface{}{
+				"message":   tc.detail,
+				"code":      float64(tc.status),
+				"requestId": "req-1",
+			}, body)
+		})
+	}
+
+	t.Run("custom problem with extensions", func(t *testing.T) {
+		problem := &Problem{
+			Type:       "https://example.com/problems/out-of-credit",
+			Title:      "You do not have enough credit",
+			Status:     http.StatusForbidden,
+			Detail:     "your current balance is 30, but that costs 50",
+			Instance:   "/account/12345/transactions/abc",
+			Extensions: map[string]interface{}{"balance": 30, "status": "ignored"},
+		}
+		_, body := write(t, ProblemFormat, problem)
+
+		require.Equal(t, map[string]interface{}{
+			"type":      "https://example.com/problems/out-of-credit",
+			"title":     "You do not have enough credit",
+			"status":    float64(http.StatusForbidden),
+			"detail":    "your current balance is 30, but that costs 50",
+			"instance":  "/account/12345/transactions/abc",
+			"balance":   float64(30),
+			"requestId": "req-1",
+		}, body)
+		require.Empty(t, problem.Extensions["requestId"], "problem is not modified")
+	})
+
+	t.Run("write problems with invalid status as internal server errors", func(t *testing.T) {
+		for _, status := range []int{0, 99, 600} {
+			rr, body := write(t, ProblemFormat, &Problem{Status: status, Detail: "x"})
+
+			require.Equal(t, http.StatusInternalServerError, rr.Code)
+			require.Equal(t, map[string]interface{}{
+				"type":      "about:blank",
+				"title":     "Internal Server Error",
+				"status":    float64(http.StatusInternalServerError),
+				"detail":    "x",
+				"instance":  "/orders/42",
+				"requestId": "req-1",
+			}, body)
+		}
+	})
+
+	t.Run("list invalid fields", func(t *testing.T) {
+		err := &ValidationError{Detail: "request is not valid", Fields: []FieldError{{Field: "name", In: "body", Message: "is required"}}}
+		fields := []interface{}{map[string]interface{}{"field": "name", "in": "body", "message": "is required"}}
+
+		_, body := write(t, ProblemFormat, err)
+		require.Equal(t, fields, body["errors"])
+
+		_, body = write(t, LegacyFormat, err)
+		require.Equal(t, fields, body["errors"])
+	})
+
+	t.Run("upstream errors wrap their cause", func(t *testing.T) {
+		cause := errors.New("connection refused")
+		err := &UpstreamError{Detail: "payments unavailable", Err: cause}
+
+		require.ErrorIs(t, err, cause)
+		require.Equal(t, "payments unavailable: connection refused", err.Error())
+	})
+}
+
+func TestErrorHandlers(t *testing.T) {
+	t.Run("render problem details when no format is selected", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		NotFound(rr, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"Route not found","instance":"/unknown"}`, rr.Body.String())
+	})
+
+	t.Run("select the legacy format through the middleware", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		ErrorFormatMiddleware(LegacyFormat)(http.HandlerFunc(MethodNotAllowed)).
+			ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/", nil))
+
+		require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		require.JSONEq(t, `{"message":"Method not allowed","code":405}`, rr.Body.String())
+	})
+}
@@ -1,19 +1,22 @@
 package response
 
 import (
-	"encoding/json"
+	"bytes"
 	"io"
 	"net/http"
 )
 
-// JSON is a convenient function to write a JSON object as response to the incoming request
+// JSON is a convenient function to write a JSON object as response to the incoming request.
+// The body is encoded before being written, so that encoding errors do not produce partial responses
 func JSON(w http.ResponseWriter, body interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if err := json.NewEncoder(w).Encode(body); err != nil {
+	buffer := new(bytes.Buffer)
+	if err := EncodeJSON(buffer, body); err != nil {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		if _, err := io.WriteString(w, `{"message":"error encoding response"}`); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		_, _ = io.WriteString(w, `{"message":"error encoding response"}`)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(buffer.Bytes())
 }
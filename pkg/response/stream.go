@@ -0,0 +1,255 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentTypeEventStream is the content type of Server-Sent Events streams
+const ContentTypeEventStream = "text/event-stream"
+
+const (
+	defaultFlushInterval = time.Second
+	defaultHeartbeat     = 15 * time.Second
+)
+
+// ErrStreamingUnsupported is returned when the response writer can not flush partial responses
+var ErrStreamingUnsupported = errors.New("response writer does not support streaming")
+
+type shutdownKey struct{}
+
+// WithShutdown returns a copy of the context carrying the channel closed when the service shuts down,
+// which stops the streamed responses written while handling requests with that context
+func WithShutdown(ctx context.Context, shutdown <-chan struct{}) context.Context {
+	return context.WithValue(ctx, shutdownKey{}, shutdown)
+}
+
+// shutdownSignal returns the channel closed on service shutdown, which is nil when not available
+func shutdownSignal(ctx context.Context) <-chan struct{} {
+	shutdown, _ := ctx.Value(shutdownKey{}).(<-chan struct{})
+	return shutdown
+}
+
+// StreamOpts configures NDJSON streamed responses
+type StreamOpts struct {
+	// FlushInterval is the maximum time written items are buffered before being sent to the client.
+	// Defaults to 1 second, while a negative interval sends each item as soon as it is written
+	FlushInterval time.Duration
+}
+
+// StreamNDJSON writes each item received from the channel as a JSON line, until the channel is closed.
+// Streaming stops without error when the client disconnects or the service shuts down, while
+// encoding and writing errors are returned to the caller once the response has already been started
+func StreamNDJSON[T any](rw http.ResponseWriter, r *http.Request, items <-chan T, opts StreamOpts) error {
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		return ErrStreamingUnsupported
+	}
+
+	interval := opts.FlushInterval
+	if interval == 0 {
+		interval = defaultFlushInterval
+	}
+
+	rw.Header().Set("Content-Type", ContentTypeNDJSON)
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	encoder := json.NewEncoder(rw)
+	pending := false
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-shutdownSignal(r.Context()):
+			flusher.Flush()
+			return nil
+		case <-tick:
+			if pending {
+				flusher.Flush()
+				pending = false
+			}
+		case item, open := <-items:
+			if !open {
+				flusher.Flush()
+				return nil
+			}
+			if err := encoder.Encode(item); err != nil {
+				return fmt.Errorf("streamed item can not be written: %w", err)
+			}
+			pending = true
+			if interval < 0 {
+				flusher.Flush()
+				pending = false
+			}
+		}
+	}
+}
+
+// StreamNDJSONSeq writes each item produced by the iterator as a JSON line (see StreamNDJSON).
+// The iterator calls yield for each item, stopping as soon as yield returns false; it should observe
+// the request context when producing an item may block
+func StreamNDJSONSeq[T any](rw http.ResponseWriter, r *http.Request, seq func(yield func(T) bool), opts StreamOpts) error {
+	items := make(chan T)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		defer close(items)
+		seq(func(item T) bool {
+			select {
+			case items <- item:
+				return true
+			case <-stop:
+				return false
+			}
+		})
+	}()
+
+	return StreamNDJSON(rw, r, items, opts)
+}
+
+// Event is a message sent through Server-Sent Events
+type Event struct {
+	// ID is stored by the client, which sends it back as Last-Event-ID header when reconnecting
+	ID string
+	// Event is the type of the event. Clients consider events without type as messages
+	Event string
+	// Data is the payload of the event. Strings and byte slices are written as they are,
+	// while any other value is encoded as JSON
+	Data interface{}
+	// Retry is the reconnection time the client waits for when the connection is lost
+	Retry time.Duration
+}
+
+// SSEOpts configures Server-Sent Events streams
+type SSEOpts struct {
+	// Retry is the reconnection time sent to the client when the stream starts
+	Retry time.Duration
+	// Heartbeat is the interval of the comments sent to keep the connection alive while no event is sent.
+	// Defaults to 15 seconds, while a negative interval disables heartbeats
+	Heartbeat time.Duration
+}
+
+// LastEventID returns the id of the last event received by the client before reconnecting,
+// so that the stream can be resumed from the following event
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
+
+// StreamSSE sends the events received from the channel as Server-Sent Events, until the channel is closed.
+// Streaming stops without error when the client disconnects or the service shuts down, while
+// invalid events and writing errors are returned to the caller once the response has already been started
+func StreamSSE(rw http.ResponseWriter, r *http.Request, events <-chan Event, opts SSEOpts) error {
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		return ErrStreamingUnsupported
+	}
+
+	heartbeat := opts.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = defaultHeartbeat
+	}
+
+	rw.Header().Set("Content-Type", ContentTypeEventStream)
+	rw.Header().Set("Cache-Control", "no-cache")
+	// disable proxy buffering, which would delay events
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	if opts.Retry > 0 {
+		if _, err := fmt.Fprintf(rw, "retry: %d\n\n", opts.Retry.Milliseconds()); err != nil {
+			return fmt.Errorf("event can not be written: %w", err)
+		}
+	}
+	flusher.Flush()
+
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-shutdownSignal(r.Context()):
+			return nil
+		case <-tick:
+			if _, err := io.WriteString(rw, ": heartbeat\n\n"); err != nil {
+				return fmt.Errorf("heartbeat can not be written: %w", err)
+			}
+			flusher.Flush()
+		case event, open := <-events:
+			if !open {
+				return nil
+			}
+			if err := writeEvent(rw, event); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// lineBreaks normalizes the line breaks allowed in event streams
+var lineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// writeEvent writes the event fields, splitting multi-line data into multiple data fields
+func writeEvent(w io.Writer, event Event) error {
+	if strings.ContainsAny(event.ID, "\r\n") || strings.ContainsAny(event.Event, "\r\n") {
+		return errors.New("event id and type can not contain line breaks")
+	}
+
+	var data string
+	switch value := event.Data.(type) {
+	case string:
+		data = value
+	case []byte:
+		data = string(value)
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("event data can not be encoded: %w", err)
+		}
+		data = string(encoded)
+	}
+
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: " + event.ID + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + event.Event + "\n")
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(lineBreaks.Replace(data), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("event can not be written: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package response
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// streamRecorder records the streamed response, allowing to read the body while it is being written
+type streamRecorder struct {
+	mu sync.Mutex
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func newStreamRecorder() *streamRecorder {
+	return &streamRecorder{ResponseRecorder: httptest.NewRecorder()}
+}
+
+func (s *streamRecorder) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ResponseRecorder.Write(b)
+}
+
+func (s *streamRecorder) WriteString(str string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ResponseRecorder.WriteString(str)
+}
+
+func (s *streamRecorder) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+	s.ResponseRecorder.Flush()
+}
+
+func (s *streamRecorder) body() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Body.String()
+}
+
+// noFlushWriter is a response writer that can not stream responses
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	t.Run("write items until the channel is closed", func(t *testing.T) {
+		items := make(chan map[string]int, 2)
+		items <- map[string]int{"id": 1}
+		items <- map[string]int{"id": 2}
+		close(items)
+
+		rr := newStreamRecorder()
+		err := StreamNDJSON(rr, httptest.NewRequest(http.MethodGet, "/export", nil), items, StreamOpts{})
+
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, ContentTypeNDJSON, rr.Header().Get("Content-Type"))
+		require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", rr.body())
+	})
+
+	t.Run("flush items periodically", func(t *testing.T) {
+		items := make(chan int, 2)
+		items <- 1
+		items <- 2
+		rr := newStreamRecorder()
+		done := make(chan error, 1)
+		go func() {
+			done <- StreamNDJSON(rr, httptest.NewRequest(http.MethodGet, "/export", nil), items, StreamOpts{FlushInterval: 100 * time.Millisecond})
+		}()
+
+		require.Eventually(t, func() bool { return rr.body() == "1\n2\n" }, time.Second, 5*time.Millisecond)
+		time.Sleep(150 * time.Millisecond)
+		rr.mu.Lock()
+		flushes := rr.flushes
+		rr.mu.Unlock()
+		require.Equal(t, 2, flushes, "headers and the pending items are flushed once")
+
+		close(items)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("flush each item with negative interval", func(t *testing.T) {
+		items := make(chan int, 3)
+		items <- 1
+		items <- 2
+		items <- 3
+		close(items)
+
+		rr := newStreamRecorder()
+		require.NoError(t, StreamNDJSON(rr, httptest.NewRequest(http.MethodGet, "/export", nil), items, StreamOpts{FlushInterval: -1}))
+		require.Equal(t, 5, rr.flushes, "headers, each item and the end of the stream are flushed")
+	})
+
+	t.Run("stop when the client disconnects", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/export", nil).WithContext(ctx)
+		items := make(chan int)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- StreamNDJSON(newStreamRecorder(), req, items, StreamOpts{})
+		}()
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("stop when the service shuts down", func(t *testing.T) {
+		shutdown := make(chan struct{})
+		req := httptest.NewRequest(http.MethodGet, "/export", nil)
+		req = req.WithContext(WithShutdown(req.Context(), shutdown))
+		items := make(chan int)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- StreamNDJSON(newStreamRecorder(), req, items, StreamOpts{})
+		}()
+
+		close(shutdown)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("return encoding errors", func(t *testing.T) {
+		items := make(chan interface{}, 1)
+		items <- func() {}
+
+		err := StreamNDJSON(newStreamRecorder(), httptest.NewRequest(http.MethodGet, "/export", nil), items, StreamOpts{})
+		require.Error(t, err)
+	})
+
+	t.Run("require a flushable response writer", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		err := StreamNDJSON(noFlushWriter{rr}, httptest.NewRequest(http.MethodGet, "/export", nil), make(chan int), StreamOpts{})
+
+		require.ErrorIs(t, err, ErrStreamingUnsupported)
+		require.False(t, rr.Flushed)
+	})
+}
+
+func TestStreamNDJSONSeq(t *testing.T) {
+	t.Run("write items produced by the iterator", func(t *testing.T) {
+		seq := func(yield func(int) bool) {
+			for i := 1; i <= 3; i++ {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+
+		rr := newStreamRecorder()
+		require.NoError(t, StreamNDJSONSeq(rr, httptest.NewRequest(http.MethodGet, "/export", nil), seq, StreamOpts{}))
+		require.Equal(t, "1\n2\n3\n", rr.body())
+	})
+
+	t.Run("stop the iterator when the client disconnects", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/export", nil).WithContext(ctx)
+
+		stopped := make(chan struct{})
+		seq := func(yield func(int) bool) {
+			defer close(stopped)
+			for i := 0; ; i++ {
+				if i == 10 {
+					cancel()
+				}
+				if !yield(i) {
+					return
+				}
+			}
+		}
+
+		require.NoError(t, StreamNDJSONSeq(newStreamRecorder(), req, seq, StreamOpts{}))
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			require.Fail(t, "iterator not stopped")
+		}
+	})
+}
+
+func TestStreamSSE(t *testing.T) {
+	t.Run("send events", func(t *testing.T) {
+		events := make(chan Event, 3)
+		events <- Event{ID: "1", Event: "order", Data: map[string]string{"id": "42"}}
+		events <- Event{Data: "first line\nsecond line"}
+		events <- Event{ID: "3", Data: []byte("raw"), Retry: 2 * time.Second}
+		close(events)
+
+		rr := newStreamRecorder()
+		err := StreamSSE(rr, httptest.NewRequest(http.MethodGet, "/events", nil), events, SSEOpts{Retry: 5 * time.Second})
+
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, ContentTypeEventStream, rr.Header().Get("Content-Type"))
+		require.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+		require.Equal(t, strings.Join([]string{
+			"retry: 5000\n",
+			"id: 1\nevent: order\ndata: {\"id\":\"42\"}\n",
+			"data: first line\ndata: second line\n",
+			"id: 3\nretry: 2000\ndata: raw\n",
+		}, "\n")+"\n", rr.body())
+	})
+
+	t.Run("send heartbeats while idle", func(t *testing.T) {
+		events := make(chan Event)
+		rr := newStreamRecorder()
+		done := make(chan error, 1)
+		go func() {
+			done <- StreamSSE(rr, httptest.NewRequest(http.MethodGet, "/events", nil), events, SSEOpts{Heartbeat: 10 * time.Millisecond})
+		}()
+
+		require.Eventually(t, func() bool { return strings.HasPrefix(rr.body(), ": heartbeat\n\n") }, time.Second, 5*time.Millisecond)
+
+		close(events)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("resume from the last event id", func(t *testing.T) {
+		history := []Event{{ID: "1", Data: "a"}, {ID: "2", Data: "b"}, {ID: "3", Data: "c"}}
+
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Last-Event-ID", "1")
+		require.Equal(t, "1", LastEventID(req))
+
+		events := make(chan Event, len(history))
+		resumed := LastEventID(req) == ""
+		for _, event := range history {
+			if resumed {
+				events <- event
+			}
+			resumed = resumed || event.ID == LastEventID(req)
+		}
+		close(events)
+
+		rr := newStreamRecorder()
+		require.NoError(t, StreamSSE(rr, req, events, SSEOpts{Heartbeat: -1}))
+		require.Equal(t, "id: 2\ndata: b\n\nid: 3\ndata: c\n\n", rr.body())
+	})
+
+	t.Run("stop when the client disconnects or the service shuts down", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		shutdown := make(chan struct{})
+		disconnected := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+		shuttingDown := httptest.NewRequest(http.MethodGet, "/events", nil)
+		shuttingDown = shuttingDown.WithContext(WithShutdown(shuttingDown.Context(), shutdown))
+
+		done := make(chan error, 2)
+		for _, req := range []*http.Request{disconnected, shuttingDown} {
+			go func(req *http.Request) {
+				done <- StreamSSE(newStreamRecorder(), req, make(chan Event), SSEOpts{})
+			}(req)
+		}
+
+		cancel()
+		close(shutdown)
+		require.NoError(t, <-done)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("reject invalid events", func(t *testing.T) {
+		for _, event := range []Event{{ID: "1\n2"}, {Event: "a\rb"}, {Data: func() {}}} {
+			events := make(chan Event, 1)
+			events <- event
+
+			err := StreamSSE(newStreamRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil), events, SSEOpts{})
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("require a flushable response writer", func(t *testing.T) {
+		err := StreamSSE(noFlushWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/events", nil), make(chan Event), SSEOpts{})
+		require.True(t, errors.Is(err, ErrStreamingUnsupported))
+	})
+}
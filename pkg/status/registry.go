@@ -0,0 +1,369 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Probe identifies the status routes a check contributes to.
+// Multiple probes can be combined with the bitwise OR operator
+type Probe uint8
+
+const (
+	// Liveness marks a check as part of the service health route
+	Liveness Probe = 1 << iota
+	// Readiness marks a check as part of the service ready route
+	Readiness
+	// CheckUp marks a check as part of the service check-up route
+	CheckUp
+	// Startup marks a check as part of the service startup route. Startup checks must
+	// all succeed once before the service is reported as started and ready
+	Startup
+)
+
+const (
+	// DefaultCheckTimeout is the maximum time a check can take when no timeout is specified
+	DefaultCheckTimeout = 5 * time.Second
+
+	statusOK = "OK"
+	statusKO = "KO"
+
+	checkLabel = "check"
+)
+
+var (
+	errCheckPending = errors.New("check has not been executed yet")
+	errCheckStale   = errors.New("check result is stale")
+)
+
+// CheckFunc verifies the status of a service dependency,
+// returning an error when the dependency is not available
+type CheckFunc func(ctx context.Context) error
+
+// Check describes a named verification executed when status routes are called
+type Check struct {
+	// Name identifies the check within the status routes responses
+	Name string
+	// Func is the function that performs the verification
+	Func CheckFunc
+	// Timeout is the maximum time the check can take before being considered failed.
+	// Defaults to DefaultCheckTimeout
+	Timeout time.Duration
+	// Critical marks the check as required for the service to work,
+	// so that its failure makes the status route return 503 - Service Unavailable
+	Critical bool
+	// Probes represents the status routes the check contributes to.
+	// Defaults to Readiness and CheckUp routes
+	Probes Probe
+	// Interval enables the background execution of the check, which is then repeated
+	// with the given period. Status routes report the last cached result rather
+	// than executing the check on each request
+	Interval time.Duration
+	// StaleAfter is the maximum age of a cached result before the check is considered failed.
+	// It applies only to background checks and defaults to three times their Interval
+	StaleAfter time.Duration
+}
+
+// CheckResult represents the outcome of a check execution
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type cachedResult struct {
+	result    CheckResult
+	updatedAt time.Time
+}
+
+// Registry collects the checks registered by the service plugins and executes them
+// concurrently when a status route is called. It implements the Status interface.
+type Registry struct {
+	mu      sync.RWMutex
+	checks  []Check
+	results map[string]cachedResult
+	gauge   *prometheus.GaugeVec
+	// startedUp is set to 1 once all the startup checks have succeeded
+	startedUp int32
+}
+
+// NewRegistry create an empty checks registry
+func NewRegistry() *Registry {
+	return &Registry{results: make(map[string]cachedResult)}
+}
+
+// RegisterMetrics export the outcome of each check execution as a gauge,
+// which is set to 1 when the check succeeds and to 0 otherwise
+func (reg *Registry) RegisterMetrics(pf promauto.Factory) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.gauge = pf.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "status_check_up",
+			Help: "outcome of the last execution of a status check",
+		},
+		[]string{checkLabel},
+	)
+}
+
+// Start launch the background execution of the registered checks that have an interval set.
+// Background checks are stopped when the provided context is done
+func (reg *Registry) Start(ctx context.Context) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	for _, check := range reg.checks {
+		if check.Interval > 0 {
+			go reg.refresh(ctx, check)
+		}
+	}
+}
+
+// refresh execute the check each interval, caching its result until the context is done
+func (reg *Registry) refresh(ctx context.Context, check Check) {
+	ticker := time.NewTicker(check.Interval)
+	defer ticker.Stop()
+
+	for {
+		result := runCheck(ctx, check)
+		if ctx.Err() != nil {
+			return
+		}
+
+		reg.mu.Lock()
+		reg.results[check.Name] = cachedResult{result: result, updatedAt: time.Now()}
+		reg.mu.Unlock()
+		reg.observe(result)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Register add the provided checks to the registry.
+// It panics when a check has no name or function, or when its name has already been registered
+func (reg *Registry) Register(checks ...Check) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	for _, check := range checks {
+		if check.Name == "" || check.Func == nil {
+			panic("status check must have both a name and a function")
+		}
+		for _, registered := range reg.checks {
+			if registered.Name == check.Name {
+				panic(fmt.Sprintf("status check %s has already been registered", check.Name))
+			}
+		}
+
+		if check.Timeout <= 0 {
+			check.Timeout = DefaultCheckTimeout
+		}
+		if check.Probes == 0 {
+			check.Probes = Readiness | CheckUp
+		}
+		if check.Interval > 0 && check.StaleAfter <= 0 {
+			check.StaleAfter = 3 * check.Interval
+		}
+
+		reg.checks = append(reg.checks, check)
+	}
+}
+
+// Run executes concurrently all the checks belonging to the given probe.
+// It returns the results following the checks registration order
+// and whether all the critical checks succeeded
+func (reg *Registry) Run(ctx context.Context, probe Probe) ([]CheckResult, bool) {
+	reg.mu.RLock()
+	checks := make([]Check, 0, len(reg.checks))
+	for _, check := range reg.checks {
+		if check.Probes&probe != 0 {
+			checks = append(checks, check)
+		}
+	}
+	reg.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			if check.Interval > 0 {
+				results[i] = reg.cached(check)
+				return
+			}
+
+			results[i] = runCheck(ctx, check)
+			reg.observe(results[i])
+		}(i, check)
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, result := range results {
+		if result.Critical && result.Status != statusOK {
+			healthy = false
+		}
+	}
+
+	return results, healthy
+}
+
+// Health returns an handler function that executes the liveness checks
+func (reg *Registry) Health(name, version string) http.HandlerFunc {
+	return reg.handler(name, version, Liveness)
+}
+
+// Ready returns an handler function that executes the readiness checks,
+// reporting the service as not ready until its startup checks have succeeded
+func (reg *Registry) Ready(name, version string) http.HandlerFunc {
+	ready := reg.handler(name, version, Readiness)
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if results, started := reg.startup(r.Context()); !started {
+			JSONResponseWithStatus(rw, http.StatusServiceUnavailable, Response{Name: name, Version: version, Status: statusKO, Checks: results})
+			return
+		}
+
+		ready(rw, r)
+	}
+}
+
+// Startup returns an handler function that executes the startup checks
+// until all of them have succeeded once
+func (reg *Registry) Startup(name, version string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		results, started := reg.startup(r.Context())
+
+		if !started {
+			JSONResponseWithStatus(rw, http.StatusServiceUnavailable, Response{Name: name, Version: version, Status: statusKO, Checks: results})
+			return
+		}
+
+		JSONResponse(rw, Response{Name: name, Version: version, Status: statusOK, Checks: results})
+	}
+}
+
+// startup executes the startup checks, unless they have already succeeded,
+// and reports whether all of them succeeded
+func (reg *Registry) startup(ctx context.Context) ([]CheckResult, bool) {
+	if atomic.LoadInt32(&reg.startedUp) == 1 {
+		return nil, true
+	}
+
+	results, _ := reg.Run(ctx, Startup)
+	for _, result := range results {
+		if result.Status != statusOK {
+			return results, false
+		}
+	}
+
+	atomic.StoreInt32(&reg.startedUp, 1)
+
+	return results, true
+}
+
+// CheckUp returns an handler function that executes the check-up checks
+func (reg *Registry) CheckUp(name, version string) http.HandlerFunc {
+	return reg.handler(name, version, CheckUp)
+}
+
+func (reg *Registry) handler(name, version string, probe Probe) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		results, healthy := reg.Run(r.Context(), probe)
+
+		if !healthy {
+			JSONResponseWithStatus(rw, http.StatusServiceUnavailable, Response{Name: name, Version: version, Status: statusKO, Checks: results})
+			return
+		}
+
+		JSONResponse(rw, Response{Name: name, Version: version, Status: statusOK, Checks: results})
+	}
+}
+
+// cached return the last result of a background check,
+// which is considered failed when missing or stale
+func (reg *Registry) cached(check Check) CheckResult {
+	reg.mu.RLock()
+	cache, found := reg.results[check.Name]
+	reg.mu.RUnlock()
+
+	switch {
+	case !found:
+		return CheckResult{Name: check.Name, Status: statusKO, Critical: check.Critical, Error: errCheckPending.Error()}
+	case time.Since(cache.updatedAt) > check.StaleAfter:
+		result := cache.result
+		result.Status = statusKO
+		result.Error = errCheckStale.Error()
+		return result
+	default:
+		return cache.result
+	}
+}
+
+// observe update the check gauge, when metrics are enabled
+func (reg *Registry) observe(result CheckResult) {
+	reg.mu.RLock()
+	gauge := reg.gauge
+	reg.mu.RUnlock()
+
+	if gauge == nil {
+		return
+	}
+
+	value := 0.0
+	if result.Status == statusOK {
+		value = 1
+	}
+	gauge.WithLabelValues(result.Name).Set(value)
+}
+
+// runCheck executes the check within its timeout, even when the check function
+// does not honour the context cancellation
+func runCheck(ctx context.Context, check Check) CheckResult {
+	checkCtx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	outcome := make(chan error, 1)
+	go func() {
+		outcome <- check.Func(checkCtx)
+	}()
+
+	var err error
+	select {
+	case err = <-outcome:
+	case <-checkCtx.Done():
+		err = checkCtx.Err()
+	}
+
+	result := CheckResult{
+		Name:      check.Name,
+		Status:    statusOK,
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = statusKO
+		result.Error = err.Error()
+	}
+
+	return result
+}
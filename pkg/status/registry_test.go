@@ -0,0 +1,340 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("verify registry implements Status interface", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			var _ Status = NewRegistry()
+		})
+	})
+
+	t.Run("empty registry behaves like the default status", func(t *testing.T) {
+		reg := NewRegistry()
+		expectedResponse := `{"name":"service-name","version":"0.0.1","status":"OK"}`
+
+		verifyStatusRequest(t, reg.Health(name, version), "/-/healthz", expectedResponse)
+		verifyStatusRequest(t, reg.Ready(name, version), "/-/ready", expectedResponse)
+		verifyStatusRequest(t, reg.CheckUp(name, version), "/-/check-up", expectedResponse)
+	})
+
+	t.Run("panic on invalid or duplicated checks", func(t *testing.T) {
+		reg := NewRegistry()
+		noop := func(ctx context.Context) error { return nil }
+
+		require.Panics(t, func() { reg.Register(Check{Name: "mongo"}) })
+		require.Panics(t, func() { reg.Register(Check{Func: noop}) })
+		require.NotPanics(t, func() { reg.Register(Check{Name: "mongo", Func: noop}) })
+		require.Panics(t, func() { reg.Register(Check{Name: "mongo", Func: noop}) })
+	})
+}
+
+func TestRegistryRun(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	t.Run("execute only checks belonging to the probe", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.Register(
+			Check{Name: "alive", Func: succeed, Probes: Liveness},
+			Check{Name: "default", Func: succeed},
+			Check{Name: "downstream", Func: succeed, Probes: CheckUp},
+		)
+
+		results, healthy := reg.Run(context.Background(), Liveness)
+		require.True(t, healthy)
+		require.Equal(t, []string{"alive"}, checkNames(results))
+
+		results, _ = reg.Run(context.Background(), Readiness)
+		require.Equal(t, []string{"default"}, checkNames(results))
+
+		results, _ = reg.Run(context.Background(), CheckUp)
+		require.Equal(t, []string{"default", "downstream"}, checkNames(results))
+	})
+
+	t.Run("execute checks concurrently", func(t *testing.T) {
+		reg := NewRegistry()
+		slow := func(ctx context.Context) error {
+			time.Sleep(200 * time.Millisecond)
+			return nil
+		}
+		reg.Register(
+			Check{Name: "first", Func: slow},
+			Check{Name: "second", Func: slow},
+			Check{Name: "third", Func: slow},
+		)
+
+		start := time.Now()
+		_, healthy := reg.Run(context.Background(), Readiness)
+
+		require.True(t, healthy)
+		require.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("non critical failures do not affect service status", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.Register(Check{Name: "cache", Func: fail(failure)})
+
+		results, healthy := reg.Run(context.Background(), Readiness)
+		require.True(t, healthy)
+		require.Equal(t, "KO", results[0].Status)
+		require.Equal(t, "connection refused", results[0].Error)
+	})
+
+	t.Run("critical failures affect service status", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.Register(
+			Check{Name: "cache", Func: succeed},
+			Check{Name: "mongo", Func: fail(failure), Critical: true},
+		)
+
+		results, healthy := reg.Run(context.Background(), Readiness)
+		require.False(t, healthy)
+		require.Equal(t, "OK", results[0].Status)
+		require.Equal(t, "KO", results[1].Status)
+	})
+
+	t.Run("checks exceeding their timeout fail", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.Register(Check{
+			Name:     "stuck",
+			Critical: true,
+			Timeout:  50 * time.Millisecond,
+			Func: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+		})
+
+		results, healthy := reg.Run(context.Background(), Readiness)
+		require.False(t, healthy)
+		require.Equal(t, context.DeadlineExceeded.Error(), results[0].Error)
+		require.Less(t, results[0].LatencyMs, float64(500))
+	})
+}
+
+func TestRegistryHandlers(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(
+		Check{Name: "cache", Func: succeed},
+		Check{Name: "mongo", Func: fail(errors.New("connection refused")), Critical: true, Probes: Readiness},
+	)
+
+	t.Run("return service unavailable when a critical check fails", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/ready", nil)
+		rr := httptest.NewRecorder()
+
+		reg.Ready(name, version).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var res Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		require.Equal(t, "KO", res.Status)
+		require.Len(t, res.Checks, 2)
+		require.Equal(t, "mongo", res.Checks[1].Name)
+		require.Equal(t, "connection refused", res.Checks[1].Error)
+		require.True(t, res.Checks[1].Critical)
+	})
+
+	t.Run("return OK when critical checks succeed", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/check-up", nil)
+		rr := httptest.NewRecorder()
+
+		reg.CheckUp(name, version).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var res Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		require.Equal(t, "OK", res.Status)
+		require.Len(t, res.Checks, 1)
+	})
+}
+
+func TestRegistryStartup(t *testing.T) {
+	t.Run("verify registry implements StartupStatus interface", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			var _ StartupStatus = NewRegistry()
+		})
+	})
+
+	t.Run("report service as not started and not ready until startup checks succeed", func(t *testing.T) {
+		reg := NewRegistry()
+		var warmedUp int32
+		reg.Register(Check{
+			Name:   "cache-preload",
+			Probes: Startup,
+			Func: func(ctx context.Context) error {
+				if atomic.LoadInt32(&warmedUp) == 0 {
+					return errors.New("cache is still loading")
+				}
+				return nil
+			},
+		})
+
+		for _, handler := range []http.HandlerFunc{reg.Startup(name, version), reg.Ready(name, version)} {
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+			var res Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			require.Equal(t, "cache is still loading", res.Checks[0].Error)
+		}
+
+		atomic.StoreInt32(&warmedUp, 1)
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/-/startup", nil)
+		rr := httptest.NewRecorder()
+		reg.Startup(name, version).ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		verifyStatusRequest(t, reg.Ready(name, version), "/-/ready", `{"name":"service-name","version":"0.0.1","status":"OK"}`)
+
+		// once startup checks succeeded they are not executed anymore
+		atomic.StoreInt32(&warmedUp, 0)
+		verifyStatusRequest(t, reg.Startup(name, version), "/-/startup", `{"name":"service-name","version":"0.0.1","status":"OK"}`)
+	})
+}
+
+func TestRegistryBackgroundChecks(t *testing.T) {
+	t.Run("report pending checks as failed before their first execution", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.Register(Check{Name: "mongo", Func: succeed, Critical: true, Interval: time.Hour})
+
+		results, healthy := reg.Run(context.Background(), Readiness)
+		require.False(t, healthy)
+		require.Equal(t, "check has not been executed yet", results[0].Error)
+	})
+
+	t.Run("status routes read cached results", func(t *testing.T) {
+		reg := NewRegistry()
+		var executions int32
+		reg.Register(Check{
+			Name:     "mongo",
+			Critical: true,
+			Interval: time.Hour,
+			Func: func(ctx context.Context) error {
+				atomic.AddInt32(&executions, 1)
+				return nil
+			},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		reg.Start(ctx)
+
+		require.Eventually(t, func() bool {
+			_, healthy := reg.Run(context.Background(), Readiness)
+			return healthy
+		}, time.Second, 10*time.Millisecond)
+
+		for i := 0; i < 5; i++ {
+			results, healthy := reg.Run(context.Background(), Readiness)
+			require.True(t, healthy)
+			require.Equal(t, "OK", results[0].Status)
+		}
+		require.Equal(t, int32(1), atomic.LoadInt32(&executions), "check is executed only in background")
+	})
+
+	t.Run("report stale results as failed", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.Register(Check{
+			Name:       "mongo",
+			Critical:   true,
+			Interval:   time.Hour,
+			StaleAfter: 100 * time.Millisecond,
+			Func:       succeed,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		reg.Start(ctx)
+
+		require.Eventually(t, func() bool {
+			_, healthy := reg.Run(context.Background(), Readiness)
+			return healthy
+		}, time.Second, 10*time.Millisecond)
+
+		time.Sleep(150 * time.Millisecond)
+
+		results, healthy := reg.Run(context.Background(), Readiness)
+		require.False(t, healthy)
+		require.Equal(t, "check result is stale", results[0].Error)
+	})
+
+	t.Run("stop background checks when context is done", func(t *testing.T) {
+		reg := NewRegistry()
+		var executions int32
+		reg.Register(Check{
+			Name:     "mongo",
+			Interval: 20 * time.Millisecond,
+			Func: func(ctx context.Context) error {
+				atomic.AddInt32(&executions, 1)
+				return nil
+			},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		reg.Start(ctx)
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+		time.Sleep(50 * time.Millisecond)
+
+		stopped := atomic.LoadInt32(&executions)
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, stopped, atomic.LoadInt32(&executions))
+	})
+}
+
+func TestRegistryMetrics(t *testing.T) {
+	reg := NewRegistry()
+	promRegistry := prometheus.NewPedanticRegistry()
+	reg.RegisterMetrics(promauto.With(promRegistry))
+
+	reg.Register(
+		Check{Name: "cache", Func: succeed},
+		Check{Name: "mongo", Func: fail(errors.New("connection refused"))},
+	)
+	reg.Run(context.Background(), Readiness)
+
+	require.Equal(t, 2, testutil.CollectAndCount(reg.gauge, "status_check_up"))
+	require.Equal(t, float64(1), testutil.ToFloat64(reg.gauge.WithLabelValues("cache")))
+	require.Equal(t, float64(0), testutil.ToFloat64(reg.gauge.WithLabelValues("mongo")))
+}
+
+func succeed(ctx context.Context) error {
+	return nil
+}
+
+func fail(err error) CheckFunc {
+	return func(ctx context.Context) error {
+		return err
+	}
+}
+
+func checkNames(results []CheckResult) []string {
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+
+	return names
+}
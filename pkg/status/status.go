@@ -16,10 +16,19 @@ type Status interface {
 	CheckUp(name, version string) http.HandlerFunc
 }
 
+// StartupStatus is an optional interface that a Status can implement
+// to compute the startup property of the service
+type StartupStatus interface {
+	// Startup returns an handler function that reports whether the service has completed its startup
+	Startup(name, version string) http.HandlerFunc
+}
+
 type Response struct {
 	Name    string `json:"name,omitempty"`
 	Version string `json:"version,omitempty"`
 	Status  string `json:"status,omitempty"`
+	// Checks lists the outcome of each check executed to compute the status
+	Checks []CheckResult `json:"checks,omitempty"`
 }
 
 type DefaultStatus struct{}
@@ -52,3 +61,15 @@ func JSONResponse(w http.ResponseWriter, res Response) {
 		}
 	}
 }
+
+// JSONResponseWithStatus write the status response using the provided HTTP status code
+func JSONResponseWithStatus(w http.ResponseWriter, statusCode int, res Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		if _, err := fmt.Fprintf(w, `{"name":%s,"version":%s,"status":KO}`, res.Name, res.Version); err != nil {
+			http.Error(w, err.Error(), statusCode)
+		}
+	}
+}
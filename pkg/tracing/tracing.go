@@ -0,0 +1,139 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	zpstd "github.com/danibix95/zeropino/middlewares/std"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracer that creates the spans of the service
+const InstrumentationName = "github.com/danibix95/miabase"
+
+const (
+	traceIDField = "traceId"
+	spanIDField  = "spanId"
+)
+
+// Opts enables the tracing of the requests received and performed by the service
+type Opts struct {
+	// TracerProvider creates the spans of the service. Tracing is disabled when it is nil
+	TracerProvider trace.TracerProvider
+	// Propagator extracts the trace context from incoming requests and injects it into the outgoing ones.
+	// Defaults to the W3C trace context and baggage propagators
+	Propagator propagation.TextMapPropagator
+}
+
+// Enabled reports whether tracing is configured
+func (opts Opts) Enabled() bool {
+	return opts.TracerProvider != nil
+}
+
+// WithDefaults returns the options filling the propagator when not provided
+func (opts Opts) WithDefaults() Opts {
+	if opts.Propagator == nil {
+		opts.Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+
+	return opts
+}
+
+// Tracer returns the tracer of the service, which is nil when tracing is disabled
+func (opts Opts) Tracer() trace.Tracer {
+	if !opts.Enabled() {
+		return nil
+	}
+
+	return opts.TracerProvider.Tracer(InstrumentationName)
+}
+
+// Middleware return a http middleware that creates a server span for each incoming request,
+// continuing the trace propagated by the caller. Spans are named after the pattern of the route
+// serving the request, so the middleware must be employed by the main chi router
+func Middleware(opts Opts) func(http.Handler) http.Handler {
+	opts = opts.WithDefaults()
+	tracer := opts.Tracer()
+
+	return func(next http.Handler) http.Handler {
+		if tracer == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := opts.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, fmt.Sprintf("HTTP %s", r.Method),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethodKey.String(r.Method),
+					semconv.HTTPTargetKey.String(r.URL.RequestURI()),
+					semconv.HTTPUserAgentKey.String(r.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(rctx.RoutePattern())
+				span.SetAttributes(semconv.HTTPRouteKey.String(rctx.RoutePattern()))
+			}
+		})
+	}
+}
+
+// Logger return a http middleware that adds the trace and span ids of the request span
+// to the request logger, therefore it must follow the middleware creating the logger
+func Logger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the default logger returned when no request logger is available must not be modified
+		logger := zpstd.Get(r.Context())
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() && logger != zpstd.Get(context.Background()) {
+			logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
+				return c.Str(traceIDField, spanContext.TraceID().String()).Str(spanIDField, spanContext.SpanID().String())
+			})
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TraceID returns the id of the trace the context belongs to, or an empty string when it is not traced
+func TraceID(ctx context.Context) string {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		return spanContext.TraceID().String()
+	}
+
+	return ""
+}
+
+// Flush exports the spans that are still buffered by the tracer provider, when it supports flushing
+func Flush(ctx context.Context, tp trace.TracerProvider) error {
+	flusher, ok := tp.(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return nil
+	}
+
+	if err := flusher.ForceFlush(ctx); err != nil {
+		return fmt.Errorf("spans can not be flushed: %w", err)
+	}
+
+	return nil
+}
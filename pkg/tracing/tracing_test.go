@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	zpstd "github.com/danibix95/zeropino/middlewares/std"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func newTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func TestMiddleware(t *testing.T) {
+	serve := func(t *testing.T, opts Opts, handler http.HandlerFunc, req *http.Request) {
+		t.Helper()
+
+		router := chi.NewRouter()
+		router.Use(Middleware(opts))
+		subrouter := chi.NewRouter()
+		subrouter.Get("/{id}", handler)
+		subrouter.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		router.Mount("/orders", subrouter)
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("create a server span named after the route pattern", func(t *testing.T) {
+		tp, exporter := newTracerProvider()
+
+		serve(t, Opts{TracerProvider: tp}, func(w http.ResponseWriter, r *http.Request) {
+			require.True(t, trace.SpanContextFromContext(r.Context()).IsValid())
+		}, httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "/orders/{id}", spans[0].Name)
+		require.Equal(t, trace.SpanKindServer, spans[0].SpanKind)
+		require.Contains(t, spans[0].Attributes, attribute.String("http.route", "/orders/{id}"))
+		require.Contains(t, spans[0].Attributes, attribute.Int("http.status_code", http.StatusOK))
+		require.False(t, spans[0].Parent.IsValid())
+	})
+
+	t.Run("continue the trace propagated by the caller", func(t *testing.T) {
+		tp, exporter := newTracerProvider()
+
+		var member string
+		req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+		req.Header.Set("traceparent", traceParent)
+		req.Header.Set("baggage", "tenant=acme")
+		serve(t, Opts{TracerProvider: tp}, func(w http.ResponseWriter, r *http.Request) {
+			member = baggage.FromContext(r.Context()).Member("tenant").Value()
+		}, req)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+		require.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+		require.True(t, spans[0].Parent.IsRemote())
+		require.Equal(t, "acme", member)
+	})
+
+	t.Run("report server errors in span status", func(t *testing.T) {
+		tp, exporter := newTracerProvider()
+
+		serve(t, Opts{TracerProvider: tp}, nil, httptest.NewRequest(http.MethodGet, "/orders/fail", nil))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "/orders/fail", spans[0].Name)
+		require.Equal(t, codes.Error, spans[0].Status.Code)
+	})
+
+	t.Run("do not trace requests when tracing is disabled", func(t *testing.T) {
+		serve(t, Opts{}, func(w http.ResponseWriter, r *http.Request) {
+			require.False(t, trace.SpanContextFromContext(r.Context()).IsValid())
+		}, httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+	})
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("add trace and span ids to the request logger", func(t *testing.T) {
+		tp, exporter := newTracerProvider()
+		buffer := new(bytes.Buffer)
+		logger := zerolog.New(buffer)
+
+		handler := Middleware(Opts{TracerProvider: tp})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					zpstd.Get(r.Context()).Info().Msg("handled")
+				})).ServeHTTP(w, r.WithContext(zpstd.WithLogger(r.Context(), &logger)))
+			}),
+		)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Contains(t, buffer.String(), `"traceId":"`+spans[0].SpanContext.TraceID().String()+`"`)
+		require.Contains(t, buffer.String(), `"spanId":"`+spans[0].SpanContext.SpanID().String()+`"`)
+	})
+
+	t.Run("leave the default logger untouched", func(t *testing.T) {
+		tp, _ := newTracerProvider()
+
+		handler := Middleware(Opts{TracerProvider: tp})(Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		buffer := new(bytes.Buffer)
+		defaultLogger := zpstd.Get(context.Background()).Output(buffer)
+		defaultLogger.Info().Msg("default")
+		require.NotContains(t, buffer.String(), "traceId")
+	})
+}
+
+func TestFlush(t *testing.T) {
+	t.Run("export buffered spans", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+
+		_, span := tp.Tracer(InstrumentationName).Start(context.Background(), "buffered")
+		span.End()
+		require.Empty(t, exporter.GetSpans())
+
+		require.NoError(t, Flush(context.Background(), tp))
+		require.Len(t, exporter.GetSpans(), 1)
+	})
+
+	t.Run("ignore providers that can not be flushed", func(t *testing.T) {
+		require.NoError(t, Flush(context.Background(), trace.NewNoopTracerProvider()))
+		require.NoError(t, Flush(context.Background(), nil))
+	})
+}
@@ -0,0 +1,332 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Locations of the request values validated against the route schemas
+const (
+	InBody        = "body"
+	InParams      = "path"
+	InQuerystring = "query"
+	InResponse    = "response"
+
+	rootField = "(root)"
+)
+
+// DefaultMaxBodySize is the maximum size in bytes of the request bodies read to be validated
+const DefaultMaxBodySize int64 = 1 << 20
+
+// ErrBodyTooLarge is returned when the request body exceeds the maximum size allowed for validation
+var ErrBodyTooLarge = errors.New("request body is too large")
+
+// ResponseMode defines how responses that do not match their schema are handled
+type ResponseMode int
+
+const (
+	// ResponseValidationOff disables the validation of responses
+	ResponseValidationOff ResponseMode = iota
+	// ResponseValidationLog logs the responses not matching their schema, which are sent unchanged
+	ResponseValidationLog
+	// ResponseValidationFail replaces the responses not matching their schema with an Internal Server Error
+	ResponseValidationFail
+)
+
+// Schemas groups the JSON Schemas describing the requests and the responses of a route.
+// Each schema can be provided either as a JSON document (string, []byte or json.RawMessage)
+// or as a Go value that is serialized to JSON, such as a map[string]interface{}
+type Schemas struct {
+	// Body is the schema of the request JSON body
+	Body interface{}
+	// Querystring is the schema of the object built from the request querystring
+	Querystring interface{}
+	// Params is the schema of the object built from the route path params
+	Params interface{}
+	// Responses associates each status code with the schema of the response JSON body
+	Responses map[int]interface{}
+}
+
+// IsEmpty reports whether no schema has been provided
+func (s Schemas) IsEmpty() bool {
+	return s.Body == nil && s.Querystring == nil && s.Params == nil && len(s.Responses) == 0
+}
+
+// compiledSchema holds a schema ready to validate documents,
+// together with the types of its properties to coerce string values
+type compiledSchema struct {
+	schema        *gojsonschema.Schema
+	propertyTypes map[string]propertyType
+}
+
+type propertyType struct {
+	kind  string
+	items string
+}
+
+// Validator checks requests and responses of a route against its schemas
+type Validator struct {
+	body        *compiledSchema
+	querystring *compiledSchema
+	params      *compiledSchema
+	responses   map[int]*compiledSchema
+}
+
+// NewValidator compiles the provided schemas, returning an error when any of them is not valid
+func NewValidator(schemas Schemas) (*Validator, error) {
+	v := &Validator{responses: make(map[int]*compiledSchema, len(schemas.Responses))}
+
+	var err error
+	if v.body, err = compile(schemas.Body); err != nil {
+		return nil, fmt.Errorf("body schema is not valid: %w", err)
+	}
+	if v.querystring, err = compile(schemas.Querystring); err != nil {
+		return nil, fmt.Errorf("querystring schema is not valid: %w", err)
+	}
+	if v.params, err = compile(schemas.Params); err != nil {
+		return nil, fmt.Errorf("params schema is not valid: %w", err)
+	}
+	for statusCode, schema := range schemas.Responses {
+		if v.responses[statusCode], err = compile(schema); err != nil {
+			return nil, fmt.Errorf("response schema for status %d is not valid: %w", statusCode, err)
+		}
+	}
+
+	return v, nil
+}
+
+// ValidateRequest checks the incoming request against the route schemas, returning the invalid fields.
+// The request body is read and then restored, so that it can be decoded again by the route handler.
+// Bodies larger than maxBodySize bytes are not read, returning ErrBodyTooLarge
+func (v *Validator) ValidateRequest(r *http.Request, maxBodySize int64) ([]response.FieldError, error) {
+	fieldErrors := make([]response.FieldError, 0)
+
+	if v.params != nil {
+		params := make(map[string]interface{})
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			for i, key := range rctx.URLParams.Keys {
+				if key != "*" {
+					params[key] = v.params.coerce(key, []string{rctx.URLParams.Values[i]})
+				}
+			}
+		}
+		fieldErrors = append(fieldErrors, v.params.validate(gojsonschema.NewGoLoader(params), InParams)...)
+	}
+
+	if v.querystring != nil {
+		query := make(map[string]interface{})
+		for key, values := range r.URL.Query() {
+			query[key] = v.querystring.coerce(key, values)
+		}
+		fieldErrors = append(fieldErrors, v.querystring.validate(gojsonschema.NewGoLoader(query), InQuerystring)...)
+	}
+
+	if v.body != nil {
+		body, err := readBody(r, maxBodySize)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			body = []byte("null")
+		}
+		if !json.Valid(body) {
+			return []response.FieldError{{Field: rootField, In: InBody, Message: "body is not a valid JSON"}}, nil
+		}
+		fieldErrors = append(fieldErrors, v.body.validate(gojsonschema.NewBytesLoader(body), InBody)...)
+	}
+
+	return fieldErrors, nil
+}
+
+// HasBodySchema reports whether the request body is validated, which requires reading it
+func (v *Validator) HasBodySchema() bool {
+	return v.body != nil
+}
+
+// HasResponseSchemas reports whether any response schema has been provided
+func (v *Validator) HasResponseSchemas() bool {
+	return len(v.responses) > 0
+}
+
+// HasResponseSchema reports whether a schema has been provided for the responses with the status code
+func (v *Validator) HasResponseSchema(statusCode int) bool {
+	_, found := v.responses[statusCode]
+	return found
+}
+
+// ValidateResponse checks the response body against the schema associated with its status code.
+// Responses whose status code has no schema are considered valid
+func (v *Validator) ValidateResponse(statusCode int, body []byte) []response.FieldError {
+	schema, found := v.responses[statusCode]
+	if !found {
+		return nil
+	}
+
+	if !json.Valid(body) {
+		return []response.FieldError{{Field: rootField, In: InResponse, Message: "response is not a valid JSON"}}
+	}
+
+	return schema.validate(gojsonschema.NewBytesLoader(body), InResponse)
+}
+
+func compile(schema interface{}) (*compiledSchema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+
+	var raw []byte
+	switch s := schema.(type) {
+	case string:
+		raw = []byte(s)
+	case []byte:
+		raw = s
+	case json.RawMessage:
+		raw = s
+	default:
+		var err error
+		if raw, err = json.Marshal(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return &compiledSchema{schema: compiled, propertyTypes: propertyTypes(raw)}, nil
+}
+
+// propertyTypes extracts the declared type of each top level property of the schema
+func propertyTypes(raw []byte) map[string]propertyType {
+	var document struct {
+		Properties map[string]struct {
+			Type  interface{} `json:"type"`
+			Items struct {
+				Type interface{} `json:"type"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	types := make(map[string]propertyType)
+
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return types
+	}
+	for name, property := range document.Properties {
+		types[name] = propertyType{kind: typeName(property.Type), items: typeName(property.Items.Type)}
+	}
+
+	return types
+}
+
+func typeName(t interface{}) string {
+	if name, ok := t.(string); ok {
+		return name
+	}
+
+	return ""
+}
+
+// coerce converts the string values of querystring and path params
+// into the type declared by the schema for the corresponding property
+func (c *compiledSchema) coerce(key string, values []string) interface{} {
+	property := c.propertyTypes[key]
+
+	if property.kind == "array" {
+		items := make([]interface{}, 0, len(values))
+		for _, value := range values {
+			items = append(items, coerceValue(property.items, value))
+		}
+		return items
+	}
+
+	return coerceValue(property.kind, values[len(values)-1])
+}
+
+func coerceValue(kind, value string) interface{} {
+	switch kind {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+
+	return value
+}
+
+func (c *compiledSchema) validate(document gojsonschema.JSONLoader, in string) []response.FieldError {
+	result, err := c.schema.Validate(document)
+	if err != nil {
+		return []response.FieldError{{Field: rootField, In: in, Message: err.Error()}}
+	}
+
+	fieldErrors := make([]response.FieldError, 0, len(result.Errors()))
+	for _, resultErr := range result.Errors() {
+		fieldErrors = append(fieldErrors, response.FieldError{
+			Field:   errorField(resultErr),
+			In:      in,
+			Message: resultErr.Description(),
+		})
+	}
+
+	return fieldErrors
+}
+
+// errorField returns the path of the invalid field, which for missing
+// required properties is the path of the property itself
+func errorField(err gojsonschema.ResultError) string {
+	field := err.Field()
+
+	if property, ok := err.Details()["property"].(string); ok && err.Type() == "required" {
+		if field == rootField {
+			return property
+		}
+		return field + "." + property
+	}
+
+	return field
+}
+
+func readBody(r *http.Request, maxBodySize int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	// one more byte is read to detect bodies exceeding the limit, including those
+	// already wrapped by http.MaxBytesReader, which fails once the limit is reached
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if int64(len(body)) > maxBodySize || (err != nil && int64(len(body)) == maxBodySize) {
+		return nil, ErrBodyTooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("request body can not be read: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// Message summarizes the invalid fields into a single message
+func Message(fieldErrors []response.FieldError) string {
+	reasons := make([]string, 0, len(fieldErrors))
+	for _, field := range fieldErrors {
+		reasons = append(reasons, fmt.Sprintf("%s %s: %s", field.In, field.Field, field.Message))
+	}
+
+	return strings.Join(reasons, ", ")
+}
@@ -0,0 +1,157 @@
+package validation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"properties": {
+		"customer": {"type": "string"},
+		"total": {"type": "number", "minimum": 0}
+	},
+	"required": ["customer"]
+}`
+
+func TestNewValidator(t *testing.T) {
+	t.Run("compile schemas provided in different formats", func(t *testing.T) {
+		_, err := NewValidator(Schemas{
+			Body:        orderSchema,
+			Querystring: map[string]interface{}{"type": "object"},
+			Params:      []byte(`{"type":"object"}`),
+			Responses:   map[int]interface{}{http.StatusOK: orderSchema},
+		})
+
+		require.NoError(t, err)
+	})
+
+	t.Run("return an error when a schema is not valid", func(t *testing.T) {
+		_, err := NewValidator(Schemas{Body: `{"type": 42}`})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "body schema is not valid")
+	})
+
+	t.Run("report empty schemas", func(t *testing.T) {
+		require.True(t, Schemas{}.IsEmpty())
+		require.False(t, Schemas{Params: orderSchema}.IsEmpty())
+	})
+}
+
+func TestValidateRequest(t *testing.T) {
+	validator, err := NewValidator(Schemas{
+		Body: orderSchema,
+		Querystring: `{
+			"type": "object",
+			"properties": {
+				"limit": {"type": "integer", "maximum": 100},
+				"archived": {"type": "boolean"},
+				"ids": {"type": "array", "items": {"type": "integer"}}
+			},
+			"additionalProperties": false
+		}`,
+		Params: `{"type": "object", "properties": {"id": {"type": "string", "pattern": "^[a-f0-9]+$"}}}`,
+	})
+	require.NoError(t, err)
+
+	t.Run("accept valid requests, coercing querystring values", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/orders/abc123?limit=10&archived=true&ids=1&ids=2", strings.NewReader(`{"customer":"mario","total":3}`))
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", "abc123")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+
+		fieldErrors, err := validator.ValidateRequest(req, DefaultMaxBodySize)
+		require.NoError(t, err)
+		require.Empty(t, fieldErrors)
+
+		body, _ := io.ReadAll(req.Body)
+		require.Equal(t, `{"customer":"mario","total":3}`, string(body), "body can be read again after validation")
+	})
+
+	t.Run("report invalid fields of each request part", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/orders/xyz?limit=1000&sort=asc", strings.NewReader(`{"total":-1}`))
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", "xyz")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+
+		fieldErrors, err := validator.ValidateRequest(req, DefaultMaxBodySize)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"path id", "query limit", "query (root)", "body customer", "body total"}, fieldNames(fieldErrors))
+	})
+
+	t.Run("report body that is not a valid JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/orders/abc", strings.NewReader(`{"customer":`))
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", "abc")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+
+		fieldErrors, err := validator.ValidateRequest(req, DefaultMaxBodySize)
+		require.NoError(t, err)
+		require.Equal(t, []response.FieldError{{Field: "(root)", In: InBody, Message: "body is not a valid JSON"}}, fieldErrors)
+	})
+
+	t.Run("report missing body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/orders/abc", http.NoBody)
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", "abc")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+
+		fieldErrors, err := validator.ValidateRequest(req, DefaultMaxBodySize)
+		require.NoError(t, err)
+		require.Len(t, fieldErrors, 1)
+		require.Equal(t, InBody, fieldErrors[0].In)
+	})
+
+	t.Run("reject body exceeding the maximum size", func(t *testing.T) {
+		body := `{"customer":"mario","total":3}`
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add("id", "abc")
+		ctx := context.WithValue(context.Background(), chi.RouteCtxKey, routeCtx)
+
+		req := httptest.NewRequest(http.MethodPost, "/orders/abc", strings.NewReader(body)).WithContext(ctx)
+		_, err := validator.ValidateRequest(req, int64(len(body)-1))
+		require.ErrorIs(t, err, ErrBodyTooLarge)
+
+		req = httptest.NewRequest(http.MethodPost, "/orders/abc", strings.NewReader(body)).WithContext(ctx)
+		req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, int64(len(body)-1))
+		_, err = validator.ValidateRequest(req, int64(len(body)-1))
+		require.ErrorIs(t, err, ErrBodyTooLarge, "limit enforced by http.MaxBytesReader")
+
+		req = httptest.NewRequest(http.MethodPost, "/orders/abc", strings.NewReader(body)).WithContext(ctx)
+		fieldErrors, err := validator.ValidateRequest(req, int64(len(body)))
+		require.NoError(t, err)
+		require.Empty(t, fieldErrors)
+	})
+}
+
+func TestValidateResponse(t *testing.T) {
+	validator, err := NewValidator(Schemas{Responses: map[int]interface{}{http.StatusOK: orderSchema}})
+	require.NoError(t, err)
+	require.True(t, validator.HasResponseSchemas())
+	require.True(t, validator.HasResponseSchema(http.StatusOK))
+	require.False(t, validator.HasResponseSchema(http.StatusNotFound))
+
+	require.Empty(t, validator.ValidateResponse(http.StatusOK, []byte(`{"customer":"mario"}`)))
+	require.Empty(t, validator.ValidateResponse(http.StatusNotFound, []byte(`not validated`)))
+	require.Equal(t, []response.FieldError{
+		{Field: "customer", In: InResponse, Message: "customer is required"},
+	}, validator.ValidateResponse(http.StatusOK, []byte(`{}`)))
+	require.Equal(t, "response customer: customer is required", Message(validator.ValidateResponse(http.StatusOK, []byte(`{}`))))
+}
+
+func fieldNames(fieldErrors []response.FieldError) []string {
+	names := make([]string, 0, len(fieldErrors))
+	for _, fieldErr := range fieldErrors {
+		names = append(names, fieldErr.In+" "+fieldErr.Field)
+	}
+
+	return names
+}
@@ -3,12 +3,26 @@ package miabase
 import (
 	"net/http"
 
+	"github.com/danibix95/miabase/pkg/acl"
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/danibix95/miabase/pkg/status"
+	"github.com/danibix95/miabase/pkg/validation"
 	"github.com/go-chi/chi/v5"
 )
 
+// Plugin groups a set of routes under the same path,
+// together with the hooks that manage its resources during the service lifecycle
 type Plugin struct {
 	Path   string
 	router *chi.Mux
+	hooks  lifecycleHooks
+	checks []status.Check
+	routes []route
+	acl    *acl.Expression
+	// responseValidation, maxBodySize and errorMapper are set by the service the plugin is registered to
+	responseValidation validation.ResponseMode
+	maxBodySize        int64
+	errorMapper        *response.ErrorMapper
 }
 
 // NewPlugin create a new plugin that groups a set of routes under it
@@ -21,8 +35,24 @@ func NewPlugin(path string) *Plugin {
 }
 
 // AddRoute add a new endpoint to the plugin associated with the logic
-// that should be executed when the route is called
-func (p *Plugin) AddRoute(method, path string, handler http.HandlerFunc) {
+// that should be executed when the route is called.
+// Optionally, route details can be provided to describe it within the service documentation,
+// to validate its requests and responses against JSON Schemas and to authorize its requests
+func (p *Plugin) AddRoute(method, path string, handler http.HandlerFunc, opts ...RouteOpts) {
+	r := route{method: method, path: path}
+	if len(opts) > 0 {
+		r.opts = opts[0]
+	}
+	if r.opts.ACL != "" {
+		r.acl = acl.MustParse(r.opts.ACL)
+	}
+	p.routes = append(p.routes, r)
+
+	if !r.opts.Schemas.IsEmpty() {
+		handler = p.withValidation(handler, r.opts.Schemas)
+	}
+	handler = p.withACL(handler, r.acl)
+
 	switch method {
 	case "GET":
 		p.router.Get(path, handler)
@@ -39,6 +69,31 @@ func (p *Plugin) AddRoute(method, path string, handler http.HandlerFunc) {
 	}
 }
 
+// OnStart register a hook that is executed once the service is listening, before it is reported
+// as started and ready. Until all start hooks complete, plugin routes reply 503 - Service Unavailable,
+// while status routes are served. When the hook returns an error the service startup is aborted
+func (p *Plugin) OnStart(hook Hook) {
+	p.hooks.start = append(p.hooks.start, hook)
+}
+
+// OnReady register a hook that is executed once the service is listening for incoming requests.
+// When the hook returns an error the service is shut down
+func (p *Plugin) OnReady(hook Hook) {
+	p.hooks.ready = append(p.hooks.ready, hook)
+}
+
+// OnShutdown register a hook that is executed after the webserver has been shut down,
+// which can be employed to release plugin resources. Shutdown hooks are executed in
+// reverse registration order and their execution is bounded by the shutdown grace period
+func (p *Plugin) OnShutdown(hook Hook) {
+	p.hooks.shutdown = append(p.hooks.shutdown, hook)
+}
+
+// AddCheck register a set of checks that the service executes when its status routes are called
+func (p *Plugin) AddCheck(checks ...status.Check) {
+	p.checks = append(p.checks, checks...)
+}
+
 // Inject allow to test plugin routes by injecting the request and recording the response
 func (p *Plugin) Inject(w http.ResponseWriter, r *http.Request) {
 	p.router.ServeHTTP(w, r)
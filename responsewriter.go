@@ -0,0 +1,143 @@
+package miabase
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+)
+
+type responseWriterKey struct{}
+
+// keepResponseWriter stores the response writer in the request context, so that restoreResponseWriter
+// can expose again the optional interfaces hidden by the middlewares executed in between
+func keepResponseWriter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), responseWriterKey{}, w)))
+	})
+}
+
+// restoreResponseWriter exposes the Hijacker, ReaderFrom and Pusher interfaces of the response writer
+// stored by keepResponseWriter, since the request logger writer only implements Flusher
+func restoreResponseWriter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		original, ok := r.Context().Value(responseWriterKey{}).(http.ResponseWriter)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(restoredResponseWriter(&loggedResponseWriter{ResponseWriter: w, original: original}), r)
+	})
+}
+
+// loggedResponseWriter writes the response through the request logger writer,
+// while the optional interfaces are served by the original writer
+type loggedResponseWriter struct {
+	http.ResponseWriter
+	original http.ResponseWriter
+}
+
+// Flush execute the Flush method of the request logger writer
+func (lrw *loggedResponseWriter) Flush() {
+	if f, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack execute the Hijack method of the original writer,
+// returning an error when it does not implement the Hijacker interface
+func (lrw *loggedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := lrw.original.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// ReadFrom execute the ReadFrom method of the original writer, which can send files without copying them.
+// Bytes sent this way are logged through the Content-Length header, since they skip the request logger writer
+func (lrw *loggedResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := lrw.original.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+
+	// hide the ReadFrom method, so that io.Copy does not call it again
+	return io.Copy(struct{ io.Writer }{lrw.ResponseWriter}, src)
+}
+
+// Push execute the Push method of the original writer,
+// returning an error when it does not implement the Pusher interface
+func (lrw *loggedResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := lrw.original.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap return the original writer, so that http.ResponseController
+// can reach the features that are not exposed by the request logger writer
+func (lrw *loggedResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.original
+}
+
+// restoredResponseWriter returns the writer exposing only the optional interfaces
+// implemented by the original one, so that type assertions on it behave as on the original
+func restoredResponseWriter(lrw *loggedResponseWriter) http.ResponseWriter {
+	type unwrapper interface {
+		http.ResponseWriter
+		http.Flusher
+		Unwrap() http.ResponseWriter
+	}
+
+	_, hijacker := lrw.original.(http.Hijacker)
+	_, readerFrom := lrw.original.(io.ReaderFrom)
+	_, pusher := lrw.original.(http.Pusher)
+
+	switch {
+	case hijacker && readerFrom && pusher:
+		return struct {
+			unwrapper
+			http.Hijacker
+			io.ReaderFrom
+			http.Pusher
+		}{lrw, lrw, lrw, lrw}
+	case hijacker && readerFrom:
+		return struct {
+			unwrapper
+			http.Hijacker
+			io.ReaderFrom
+		}{lrw, lrw, lrw}
+	case hijacker && pusher:
+		return struct {
+			unwrapper
+			http.Hijacker
+			http.Pusher
+		}{lrw, lrw, lrw}
+	case readerFrom && pusher:
+		return struct {
+			unwrapper
+			io.ReaderFrom
+			http.Pusher
+		}{lrw, lrw, lrw}
+	case hijacker:
+		return struct {
+			unwrapper
+			http.Hijacker
+		}{lrw, lrw}
+	case readerFrom:
+		return struct {
+			unwrapper
+			io.ReaderFrom
+		}{lrw, lrw}
+	case pusher:
+		return struct {
+			unwrapper
+			http.Pusher
+		}{lrw, lrw}
+	default:
+		return struct {
+			unwrapper
+		}{lrw}
+	}
+}
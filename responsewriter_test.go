@@ -0,0 +1,42 @@
+package miabase
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreResponseWriter(t *testing.T) {
+	s := NewService(ServiceOpts{LogLevel: logLevel})
+
+	var handlerWriter http.ResponseWriter
+	plugin := NewPlugin("/")
+	plugin.AddRoute(http.MethodGet, "/hello", func(rw http.ResponseWriter, r *http.Request) {
+		handlerWriter = rw
+		rw.WriteHeader(http.StatusAccepted)
+		rw.(http.Flusher).Flush()
+	})
+	s.Register(plugin)
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/hello", nil)
+	rr := httptest.NewRecorder()
+	s.Inject(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code, "Status codes mismatch")
+	require.True(t, rr.Flushed)
+
+	_, isHijacker := handlerWriter.(http.Hijacker)
+	_, isReaderFrom := handlerWriter.(io.ReaderFrom)
+	_, isPusher := handlerWriter.(http.Pusher)
+	require.False(t, isHijacker, "interfaces not implemented by the original writer are not exposed")
+	require.False(t, isReaderFrom, "interfaces not implemented by the original writer are not exposed")
+	require.False(t, isPusher, "interfaces not implemented by the original writer are not exposed")
+
+	unwrapped, ok := handlerWriter.(interface{ Unwrap() http.ResponseWriter })
+	require.True(t, ok, "Unwrap is always available")
+	require.NotNil(t, unwrapped.Unwrap())
+}
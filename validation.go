@@ -0,0 +1,146 @@
+package miabase
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/danibix95/miabase/pkg/validation"
+	"github.com/danibix95/zeropino"
+	zpstd "github.com/danibix95/zeropino/middlewares/std"
+)
+
+// withValidation wraps the handler so that incoming requests are validated against the route schemas
+// before executing it. Depending on the service configuration, its responses are validated as well.
+// It panics when any of the provided schemas is not valid
+func (p *Plugin) withValidation(handler http.HandlerFunc, schemas validation.Schemas) http.HandlerFunc {
+	validator, err := validation.NewValidator(schemas)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		maxBodySize := p.maxBodySize
+		if maxBodySize <= 0 {
+			maxBodySize = validation.DefaultMaxBodySize
+		}
+		if validator.HasBodySchema() && r.Body != nil {
+			r.Body = http.MaxBytesReader(rw, r.Body, maxBodySize)
+		}
+
+		fieldErrors, err := validator.ValidateRequest(r, maxBodySize)
+		if errors.Is(err, validation.ErrBodyTooLarge) {
+			detail := fmt.Sprintf("request body exceeds the maximum size of %d bytes", maxBodySize)
+			response.WriteProblem(rw, r, response.NewProblem(http.StatusRequestEntityTooLarge, detail))
+			return
+		}
+		if err != nil {
+			response.BadRequest(rw, r, err.Error())
+			return
+		}
+		if len(fieldErrors) > 0 {
+			response.BadRequest(rw, r, "request validation failed", fieldErrors...)
+			return
+		}
+
+		if p.responseValidation == validation.ResponseValidationOff || !validator.HasResponseSchemas() {
+			handler(rw, r)
+			return
+		}
+
+		buffered := &bufferedResponse{rw: rw, header: make(http.Header), buffered: validator.HasResponseSchema}
+		handler(buffered, r)
+		if !buffered.wroteHeader {
+			buffered.WriteHeader(http.StatusOK)
+		}
+		if buffered.passthrough {
+			return
+		}
+
+		if fieldErrors := validator.ValidateResponse(buffered.status, buffered.body.Bytes()); len(fieldErrors) > 0 {
+			logger := zpstd.Get(r.Context())
+			if logger == nil {
+				logger = zeropino.InitDefault()
+			}
+			logger.Error().Int("status", buffered.status).Msg("response does not match its schema: " + validation.Message(fieldErrors))
+
+			if p.responseValidation == validation.ResponseValidationFail {
+				response.InternalServerError(rw, r)
+				return
+			}
+		}
+
+		buffered.flushTo(rw)
+	}
+}
+
+// bufferedResponse collects the response written by an handler, so that it can be validated before being sent.
+// Responses whose status has no schema are not buffered, but written straight to the wrapped writer
+type bufferedResponse struct {
+	rw     http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+	// buffered reports whether the responses with the status code must be buffered
+	buffered    func(statusCode int) bool
+	wroteHeader bool
+	passthrough bool
+}
+
+func (br *bufferedResponse) Header() http.Header {
+	if br.passthrough {
+		return br.rw.Header()
+	}
+	return br.header
+}
+
+func (br *bufferedResponse) Write(body []byte) (int, error) {
+	if !br.wroteHeader {
+		br.WriteHeader(http.StatusOK)
+	}
+	if br.passthrough {
+		return br.rw.Write(body)
+	}
+	return br.body.Write(body)
+}
+
+// WriteHeader stores the status code of buffered responses, while it sends the collected headers
+// when the status has no schema, so that the response is written without being buffered
+func (br *bufferedResponse) WriteHeader(statusCode int) {
+	if br.wroteHeader {
+		return
+	}
+	br.wroteHeader = true
+	br.status = statusCode
+	if br.buffered(statusCode) {
+		return
+	}
+
+	br.passthrough = true
+	for key, values := range br.header {
+		br.rw.Header()[key] = values
+	}
+	br.rw.WriteHeader(statusCode)
+}
+
+// Flush sends the written data to the client when the response is not buffered,
+// while it has no effect on buffered responses, which are sent once validated
+func (br *bufferedResponse) Flush() {
+	if !br.wroteHeader {
+		br.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := br.rw.(http.Flusher); ok && br.passthrough {
+		flusher.Flush()
+	}
+}
+
+// flushTo sends the collected response through the provided writer
+func (br *bufferedResponse) flushTo(rw http.ResponseWriter) {
+	for key, values := range br.header {
+		rw.Header()[key] = values
+	}
+	rw.WriteHeader(br.status)
+	_, _ = rw.Write(br.body.Bytes())
+}
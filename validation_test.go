@@ -0,0 +1,141 @@
+package miabase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danibix95/miabase/pkg/response"
+	"github.com/danibix95/miabase/pkg/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteValidation(t *testing.T) {
+	schemas := validation.Schemas{
+		Body: `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`,
+		Responses: map[int]interface{}{
+			http.StatusOK: `{"type":"object","properties":{"message":{"type":"string"}},"required":["message"]}`,
+		},
+	}
+
+	newService := func(opts ServiceOpts) *Service {
+		opts.LogLevel = logLevel
+		s := NewService(opts)
+
+		plugin := NewPlugin("/")
+		plugin.AddRoute(http.MethodPost, "/greet", func(rw http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.RawQuery, "stream") {
+				rw.WriteHeader(http.StatusAccepted)
+				_, _ = rw.Write([]byte("processing"))
+				rw.(http.Flusher).Flush()
+				return
+			}
+			if strings.Contains(r.URL.RawQuery, "broken") {
+				response.JSON(rw, map[string]int{"greeting": 42})
+				return
+			}
+			response.JSON(rw, map[string]string{"message": "welcome"})
+		}, RouteOpts{Schemas: schemas})
+		s.Register(plugin)
+
+		return s
+	}
+
+	t.Run("reject requests not matching the schema", func(t *testing.T) {
+		s := newService(ServiceOpts{})
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/greet", strings.NewReader(`{}`))
+		req.Header.Set("x-request-id", "req-1")
+		rr := httptest.NewRecorder()
+		s.Inject(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code, "Status codes mismatch")
+		verifyJSONResponse(t, rr, map[string]interface{}{
+			"type":      "about:blank",
+			"title":     "Bad Request",
+			"status":    float64(http.StatusBadRequest),
+			"detail":    "request validation failed",
+			"instance":  "/greet",
+			"requestId": "req-1",
+			"errors": []interface{}{
+				map[string]interface{}{"field": "name", "in": "body", "message": "name is required"},
+			},
+		})
+	})
+
+	t.Run("accept requests matching the schema", func(t *testing.T) {
+		s := newService(ServiceOpts{})
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/greet", strings.NewReader(`{"name":"mario"}`))
+		rr := httptest.NewRecorder()
+		s.Inject(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, "Status codes mismatch")
+		verifyJSONResponse(t, rr, map[string]interface{}{"message": "welcome"})
+	})
+
+	testCases := []struct {
+		mode           validation.ResponseMode
+		expectedStatus int
+		testName       string
+	}{
+		{validation.ResponseValidationOff, http.StatusOK, "send mismatching responses when validation is off"},
+		{validation.ResponseValidationLog, http.StatusOK, "send mismatching responses when validation only logs"},
+		{validation.ResponseValidationFail, http.StatusInternalServerError, "fail mismatching responses when validation fails"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			s := newService(ServiceOpts{ResponseValidation: tc.mode})
+
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/greet?broken", strings.NewReader(`{"name":"mario"}`))
+			rr := httptest.NewRecorder()
+			s.Inject(rr, req)
+
+			require.Equal(t, tc.expectedStatus, rr.Code, "Status codes mismatch")
+		})
+	}
+
+	t.Run("reject bodies exceeding the maximum size", func(t *testing.T) {
+		s := newService(ServiceOpts{MaxBodySize: 8})
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/greet", strings.NewReader(`{"name":"mario"}`))
+		req.Header.Set("x-request-id", "req-2")
+		rr := httptest.NewRecorder()
+		s.Inject(rr, req)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, "Status codes mismatch")
+		verifyJSONResponse(t, rr, map[string]interface{}{
+			"type":      "about:blank",
+			"title":     "Request Entity Too Large",
+			"status":    float64(http.StatusRequestEntityTooLarge),
+			"detail":    "request body exceeds the maximum size of 8 bytes",
+			"instance":  "/greet",
+			"requestId": "req-2",
+		})
+	})
+
+	t.Run("stream responses whose status has no schema", func(t *testing.T) {
+		s := newService(ServiceOpts{ResponseValidation: validation.ResponseValidationFail})
+
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/greet?stream", strings.NewReader(`{"name":"mario"}`))
+		rr := httptest.NewRecorder()
+		s.Inject(rr, req)
+
+		require.Equal(t, http.StatusAccepted, rr.Code, "Status codes mismatch")
+		require.True(t, rr.Flushed, "flushes reach the underlying writer")
+		require.Equal(t, "processing", rr.Body.String())
+	})
+
+	t.Run("panic when a schema is not valid", func(t *testing.T) {
+		plugin := NewPlugin("/")
+
+		require.Panics(t, func() {
+			plugin.AddRoute(http.MethodGet, "/", func(rw http.ResponseWriter, r *http.Request) {}, RouteOpts{
+				Schemas: validation.Schemas{Querystring: `{"type":`},
+			})
+		})
+	})
+}